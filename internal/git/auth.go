@@ -0,0 +1,257 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+const (
+	// credentialsEnvPrefix is the prefix of the environment variables containing the credentials
+	// referenced by name in the configuration
+	credentialsEnvPrefix = "VAB_CREDENTIALS_"
+
+	usernameEnvSuffix       = "_USERNAME"
+	passwordEnvSuffix       = "_PASSWORD"
+	tokenEnvSuffix          = "_TOKEN"
+	sshKeyEnvSuffix         = "_SSH_KEY"
+	sshKeyPasswordEnvSuffix = "_SSH_KEY_PASSWORD"
+
+	// defaultTokenUsername is the username sent with a token if no other is specified, git servers
+	// accepting tokens via basic auth ignore it but it cannot be empty
+	defaultTokenUsername = "vab"
+	// defaultSSHUsername is the username used for ssh connections if none is set in the url
+	defaultSSHUsername = "git"
+
+	netrcEnvName = "NETRC"
+)
+
+// defaultSSHKeyFiles are the names of the private keys searched in the .ssh folder of the user home when
+// the ssh agent is not available, in order of preference
+var defaultSSHKeyFiles = []string{"id_ed25519", "id_ecdsa", "id_rsa"}
+
+// credentialsEnvName return the name of the environment variable containing the value with suffix
+// for the credentials with name
+func credentialsEnvName(name, suffix string) string {
+	sanitizedName := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+
+	return credentialsEnvPrefix + sanitizedName + suffix
+}
+
+// remoteAuth return an AuthMethod for connecting to url using the credentials referenced by name.
+// When name is empty or its variables are not set the http connections will use the netrc file and
+// the ssh ones the ssh agent or the default private keys of the user; if nothing is found a nil AuthMethod
+// is returned for http, and an error for ssh
+func remoteAuth(url, credentials string) (transport.AuthMethod, error) {
+	endpoint, err := transport.NewEndpoint(url)
+	if err != nil {
+		return nil, fmt.Errorf("parsing repository url: %w", err)
+	}
+
+	switch endpoint.Protocol {
+	case "http", "https":
+		return httpAuth(endpoint, credentials)
+	case "ssh":
+		return sshAuth(endpoint, credentials)
+	default:
+		return nil, nil
+	}
+}
+
+// httpAuth return a basic auth for endpoint with the username and password or token contained in
+// the credentials variables, or with the ones found in the netrc file for the endpoint host
+func httpAuth(endpoint *transport.Endpoint, credentials string) (transport.AuthMethod, error) {
+	if len(credentials) > 0 {
+		username := os.Getenv(credentialsEnvName(credentials, usernameEnvSuffix))
+		if token := os.Getenv(credentialsEnvName(credentials, tokenEnvSuffix)); len(token) > 0 {
+			if len(username) == 0 {
+				username = defaultTokenUsername
+			}
+			return &http.BasicAuth{Username: username, Password: token}, nil
+		}
+
+		if password := os.Getenv(credentialsEnvName(credentials, passwordEnvSuffix)); len(password) > 0 {
+			return &http.BasicAuth{Username: username, Password: password}, nil
+		}
+	}
+
+	username, password, err := netrcCredentials(endpoint.Host)
+	switch {
+	case err != nil:
+		return nil, err
+	case len(username) > 0 || len(password) > 0:
+		return &http.BasicAuth{Username: username, Password: password}, nil
+	case len(credentials) > 0:
+		return nil, fmt.Errorf("no credentials found for %q: set %s or %s", credentials,
+			credentialsEnvName(credentials, tokenEnvSuffix), credentialsEnvName(credentials, passwordEnvSuffix))
+	default:
+		return nil, nil
+	}
+}
+
+// sshAuth return the private key contained in the credentials variables for endpoint, or fallback
+// to the ssh agent and then to the default private keys of the user. An error is returned only if none
+// of them can be used
+func sshAuth(endpoint *transport.Endpoint, credentials string) (transport.AuthMethod, error) {
+	username := endpoint.User
+	if len(username) == 0 {
+		username = defaultSSHUsername
+	}
+
+	keyPassword := ""
+	if len(credentials) > 0 {
+		keyPassword = os.Getenv(credentialsEnvName(credentials, sshKeyPasswordEnvSuffix))
+		if keyPath := os.Getenv(credentialsEnvName(credentials, sshKeyEnvSuffix)); len(keyPath) > 0 {
+			auth, err := ssh.NewPublicKeysFromFile(username, keyPath, keyPassword)
+			if err != nil {
+				return nil, fmt.Errorf("reading ssh key for %q: %w", credentials, err)
+			}
+			return auth, nil
+		}
+	}
+
+	auth, agentErr := ssh.NewSSHAgentAuth(username)
+	if agentErr == nil {
+		return auth, nil
+	}
+
+	keyAuth, keyErr := defaultSSHKeyAuth(username, keyPassword)
+	if keyAuth != nil {
+		return keyAuth, nil
+	}
+
+	errs := []error{fmt.Errorf("connecting to ssh agent: %w", agentErr), keyErr}
+	if len(credentials) > 0 {
+		errs = append(errs, fmt.Errorf("%s is not set", credentialsEnvName(credentials, sshKeyEnvSuffix)))
+	}
+	return nil, fmt.Errorf("no ssh credentials found for %s: %w", endpoint.Host, errors.Join(errs...))
+}
+
+// defaultSSHKeyAuth return the first of the default private keys found in the .ssh folder of the user home
+// that can be read with keyPassword, or an error describing why none of them can be used
+func defaultSSHKeyAuth(username, keyPassword string) (transport.AuthMethod, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("looking for ssh keys: %w", err)
+	}
+
+	errs := make([]error, 0)
+	for _, name := range defaultSSHKeyFiles {
+		keyPath := filepath.Join(home, ".ssh", name)
+		if _, err := os.Stat(keyPath); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		auth, err := ssh.NewPublicKeysFromFile(username, keyPath, keyPassword)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("reading ssh key %s: %w", keyPath, err))
+			continue
+		}
+		return auth, nil
+	}
+
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("no ssh key found in %s", filepath.Join(home, ".ssh"))
+	}
+	return nil, errors.Join(errs...)
+}
+
+// netrcCredentials return the login and password found for host in the netrc file, the file is read from
+// the path in the NETRC environment variable or from the user home directory
+func netrcCredentials(host string) (string, string, error) {
+	path := os.Getenv(netrcEnvName)
+	if len(path) == 0 {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", nil
+		}
+		path = filepath.Join(home, ".netrc")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", "", nil
+		}
+		return "", "", fmt.Errorf("reading netrc file: %w", err)
+	}
+
+	login, password := parseNetrc(string(data), host)
+	return login, password, nil
+}
+
+// parseNetrc return the login and password for host contained in data, falling back to the default entry
+// if present. Macro definitions are not supported
+func parseNetrc(data, host string) (string, string) {
+	type entry struct{ login, password string }
+	var hostEntry, defaultEntry *entry
+	var current *entry
+
+	fields := strings.Fields(data)
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "machine":
+			current = &entry{}
+			if i+1 < len(fields) && fields[i+1] == host && hostEntry == nil {
+				hostEntry = current
+			}
+			i++
+		case "default":
+			current = &entry{}
+			if defaultEntry == nil {
+				defaultEntry = current
+			}
+		case "login", "password", "account":
+			if current == nil || i+1 >= len(fields) {
+				continue
+			}
+			switch fields[i] {
+			case "login":
+				current.login = fields[i+1]
+			case "password":
+				current.password = fields[i+1]
+			}
+			i++
+		}
+	}
+
+	switch {
+	case hostEntry != nil:
+		return hostEntry.login, hostEntry.password
+	case defaultEntry != nil:
+		return defaultEntry.login, defaultEntry.password
+	default:
+		return "", ""
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteAuth(t *testing.T) {
+	tmpDir := t.TempDir()
+	netrcPath := filepath.Join(tmpDir, "netrc")
+	netrc := "machine git.example.com login netrc-user password netrc-password\n"
+	require.NoError(t, os.WriteFile(netrcPath, []byte(netrc), 0600))
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keyBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	keyPath := filepath.Join(tmpDir, "id_ed25519")
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyBytes}), 0600))
+
+	homeWithKey := filepath.Join(tmpDir, "home")
+	require.NoError(t, os.MkdirAll(filepath.Join(homeWithKey, ".ssh"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(homeWithKey, ".ssh", "id_ed25519"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyBytes}), 0600))
+
+	tests := map[string]struct {
+		url           string
+		credentials   string
+		env           map[string]string
+		expectedAuth  transport.AuthMethod
+		expectedError string
+	}{
+		"local repository don't need auth": {
+			url:         "file:///tmp/repo.git",
+			credentials: "mirror",
+		},
+		"public repository without credentials": {
+			url: "https://github.com/mia-platform/distribution",
+		},
+		"token from environment": {
+			url:         "https://git.example.com/distribution.git",
+			credentials: "private-mirror",
+			env: map[string]string{
+				"VAB_CREDENTIALS_PRIVATE_MIRROR_TOKEN": "token",
+			},
+			expectedAuth: &http.BasicAuth{Username: defaultTokenUsername, Password: "token"},
+		},
+		"username and password from environment": {
+			url:         "https://git.example.com/distribution.git",
+			credentials: "mirror",
+			env: map[string]string{
+				"VAB_CREDENTIALS_MIRROR_USERNAME": "user",
+				"VAB_CREDENTIALS_MIRROR_PASSWORD": "password",
+			},
+			expectedAuth: &http.BasicAuth{Username: "user", Password: "password"},
+		},
+		"fallback to netrc file": {
+			url:         "https://git.example.com/distribution.git",
+			credentials: "mirror",
+			env: map[string]string{
+				netrcEnvName: netrcPath,
+			},
+			expectedAuth: &http.BasicAuth{Username: "netrc-user", Password: "netrc-password"},
+		},
+		"missing credentials": {
+			url:         "https://git.other.com/distribution.git",
+			credentials: "mirror",
+			env: map[string]string{
+				netrcEnvName: netrcPath,
+			},
+			expectedError: `no credentials found for "mirror"`,
+		},
+		"ssh key from environment": {
+			url:         "ssh://deploy@git.example.com/distribution.git",
+			credentials: "mirror",
+			env: map[string]string{
+				"VAB_CREDENTIALS_MIRROR_SSH_KEY": keyPath,
+			},
+		},
+		"invalid ssh key path": {
+			url:         "git@git.example.com:distribution.git",
+			credentials: "mirror",
+			env: map[string]string{
+				"VAB_CREDENTIALS_MIRROR_SSH_KEY": filepath.Join(tmpDir, "missing"),
+			},
+			expectedError: `reading ssh key for "mirror"`,
+		},
+		"fallback to the default ssh key without agent": {
+			url:         "ssh://deploy@git.example.com/distribution.git",
+			credentials: "mirror",
+			env: map[string]string{
+				"SSH_AUTH_SOCK": "",
+				"HOME":          homeWithKey,
+			},
+		},
+		"no agent and no ssh key": {
+			url:         "ssh://deploy@git.example.com/distribution.git",
+			credentials: "mirror",
+			env: map[string]string{
+				"SSH_AUTH_SOCK": "",
+				"HOME":          tmpDir,
+			},
+			expectedError: "no ssh credentials found for git.example.com",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(netrcEnvName, filepath.Join(tmpDir, "missing"))
+			for key, value := range test.env {
+				t.Setenv(key, value)
+			}
+
+			auth, err := remoteAuth(test.url, test.credentials)
+			if len(test.expectedError) > 0 {
+				assert.ErrorContains(t, err, test.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			if keys, ok := auth.(*ssh.PublicKeys); ok {
+				assert.Equal(t, "deploy", keys.User)
+				return
+			}
+			assert.Equal(t, test.expectedAuth, auth)
+		})
+	}
+}
+
+func TestParseNetrc(t *testing.T) {
+	t.Parallel()
+
+	data := `machine git.example.com
+	login user
+	password secret
+
+machine other.example.com login other password other-secret account ignored
+default login anonymous password guest
+`
+	tests := map[string]struct {
+		host             string
+		expectedLogin    string
+		expectedPassword string
+	}{
+		"multiline entry": {
+			host:             "git.example.com",
+			expectedLogin:    "user",
+			expectedPassword: "secret",
+		},
+		"single line entry": {
+			host:             "other.example.com",
+			expectedLogin:    "other",
+			expectedPassword: "other-secret",
+		},
+		"default entry": {
+			host:             "missing.example.com",
+			expectedLogin:    "anonymous",
+			expectedPassword: "guest",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			login, password := parseNetrc(data, test.host)
+			assert.Equal(t, test.expectedLogin, login)
+			assert.Equal(t, test.expectedPassword, password)
+		})
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/osfs"
+	billyutil "github.com/go-git/go-billy/v5/util"
+
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
+)
+
+const (
+	cacheDirName = "vab"
+	// cachePackagesDirName is versioned for allowing future changes to the cache layout
+	cachePackagesDirName = "packages-v1"
+
+	cacheFilesDirName   = "files"
+	cacheCommitFileName = "commit"
+
+	filePermission = 0644
+)
+
+var (
+	// ErrPackageNotCached is returned when a package is requested in offline mode and is not present in the cache
+	ErrPackageNotCached = errors.New("package not found in cache")
+	// ErrOffline is returned when an operation that needs the remote repository is requested in offline mode
+	ErrOffline = errors.New("remote repository not available in offline mode")
+)
+
+// Cache is an on disk storage for the repositories cloned for the packages, every entry is addressed by
+// the repository url and the tag of the package and contains the files of the repository and the cloned commit
+type Cache struct {
+	path string
+}
+
+// NewCache return a Cache that store its data in path
+func NewCache(path string) *Cache {
+	return &Cache{path: path}
+}
+
+// DefaultCachePath return the path of the cache inside the user cache directory
+func DefaultCachePath() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("finding cache directory: %w", err)
+	}
+
+	return filepath.Join(cacheDir, cacheDirName, cachePackagesDirName), nil
+}
+
+// keyForPackage return the key of the cache entry for pkg
+func keyForPackage(pkg v1alpha1.Package) string {
+	sum := sha256.Sum256([]byte(remoteURL(pkg) + "\n" + tagReferenceForPackage(pkg).String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// get return a filesystem for the cache entry with key and the commit it was cloned from if present
+func (c *Cache) get(key string) (billy.Filesystem, string, bool) {
+	entryPath := filepath.Join(c.path, key)
+	commit, err := os.ReadFile(filepath.Join(entryPath, cacheCommitFileName))
+	if err != nil {
+		return nil, "", false
+	}
+
+	return osfs.New(filepath.Join(entryPath, cacheFilesDirName), osfs.WithBoundOS()), string(commit), true
+}
+
+// store copies all the files contained in fsys in the cache entry with key together with the commit they
+// come from, and return a filesystem for it.
+// The data is written in a temporary folder and then moved to its final location for avoiding incomplete entries
+func (c *Cache) store(key string, fsys billy.Filesystem, commit string) (billy.Filesystem, string, error) {
+	if err := os.MkdirAll(c.path, os.ModePerm); err != nil {
+		return nil, "", fmt.Errorf("creating cache directory: %w", err)
+	}
+
+	tmpPath, err := os.MkdirTemp(c.path, "tmp-"+key)
+	if err != nil {
+		return nil, "", fmt.Errorf("creating cache entry: %w", err)
+	}
+	defer os.RemoveAll(tmpPath)
+
+	filesPath := filepath.Join(tmpPath, cacheFilesDirName)
+	err = billyutil.Walk(fsys, "/", func(filePath string, info fs.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		return copyFile(fsys, filePath, filepath.Join(filesPath, filePath), info.Mode())
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("writing cache entry: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(tmpPath, cacheCommitFileName), []byte(commit), filePermission); err != nil {
+		return nil, "", fmt.Errorf("writing cache entry: %w", err)
+	}
+
+	// another process can have created the same entry in the meantime, in that case we use it
+	if err := os.Rename(tmpPath, filepath.Join(c.path, key)); err != nil {
+		if cachedFs, cachedCommit, found := c.get(key); found {
+			return cachedFs, cachedCommit, nil
+		}
+		return nil, "", fmt.Errorf("writing cache entry: %w", err)
+	}
+
+	cachedFs, _, _ := c.get(key)
+	return cachedFs, commit, nil
+}
+
+// remove deletes the cache entry with key, if present.
+// The entry is moved to a temporary folder before deleting it for never leaving an incomplete entry
+func (c *Cache) remove(key string) error {
+	tmpPath, err := os.MkdirTemp(c.path, "old-"+key)
+	if err != nil {
+		return fmt.Errorf("removing cache entry: %w", err)
+	}
+	defer os.RemoveAll(tmpPath)
+
+	if err := os.Rename(filepath.Join(c.path, key), filepath.Join(tmpPath, key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("removing cache entry: %w", err)
+	}
+	return nil
+}
+
+// copyFile copies the file at path in fsys to targetPath on disk, keeping its permissions
+func copyFile(fsys billy.Filesystem, path, targetPath string, mode fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(targetPath), os.ModePerm); err != nil {
+		return err
+	}
+
+	file, err := fsys.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	outFile, err := os.OpenFile(targetPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+	defer outFile.Close()
+
+	_, err = io.Copy(outFile, file)
+	return err
+}
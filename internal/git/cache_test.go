@@ -0,0 +1,147 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
+)
+
+func TestCachedFilesGetter(t *testing.T) {
+	t.Parallel()
+
+	filePath := filepath.Join("addons", "category", "test-addon", "file1.yaml")
+	repoPath := newLocalRepository(t, "addon-category-test-addon-1.0.0", filePath)
+	cachePath := t.TempDir()
+
+	pkg := v1alpha1.NewAddon(t, "category/test-addon", "1.0.0", false)
+	pkg.Source = v1alpha1.Source{URL: "file://" + repoPath}
+
+	// offline mode with an empty cache must fail without contacting the repository
+	files, _, err := NewCachedFilesGetter(NewCache(cachePath), true).GetFilesForPackage(pkg)
+	assert.ErrorIs(t, err, ErrPackageNotCached)
+	assert.Nil(t, files)
+
+	files, revision, err := NewCachedFilesGetter(NewCache(cachePath), false).GetFilesForPackage(pkg)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.DirExists(t, filepath.Join(cachePath, keyForPackage(pkg)))
+
+	// remove the repository for ensuring that the files are read from the cache
+	require.NoError(t, os.RemoveAll(repoPath))
+	offlineGetter := NewCachedFilesGetter(NewCache(cachePath), true)
+	files, cachedRevision, err := offlineGetter.GetFilesForPackage(pkg)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "file1.yaml", files[0].path)
+	assert.Equal(t, revision, cachedRevision)
+
+	remoteRevision, err := offlineGetter.RemoteRevision(pkg)
+	require.NoError(t, err)
+	assert.Equal(t, revision, remoteRevision)
+
+	targetPath := t.TempDir()
+	require.NoError(t, files[0].WriteContent(targetPath))
+	content, err := os.ReadFile(filepath.Join(targetPath, "file1.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "content\n", string(content))
+}
+
+func TestCachedFilesGetterWithMovedTag(t *testing.T) {
+	t.Parallel()
+
+	tag := "addon-category-test-addon-1.0.0"
+	filePath := filepath.Join("addons", "category", "test-addon", "file1.yaml")
+	repoPath := newLocalRepository(t, tag, filePath)
+	cachePath := t.TempDir()
+
+	pkg := v1alpha1.NewAddon(t, "category/test-addon", "1.0.0", false)
+	pkg.Source = v1alpha1.Source{URL: "file://" + repoPath}
+	_, revision, err := NewCachedFilesGetter(NewCache(cachePath), false).GetFilesForPackage(pkg)
+	require.NoError(t, err)
+
+	// move the tag to a new commit changing the file content
+	repo, err := git.PlainOpen(repoPath)
+	require.NoError(t, err)
+	worktree, err := repo.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, filePath), []byte("new content\n"), 0600))
+	_, err = worktree.Add(".")
+	require.NoError(t, err)
+	hash, err := worktree.Commit("update "+filePath, &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteTag(tag))
+	_, err = repo.CreateTag(tag, hash, nil)
+	require.NoError(t, err)
+
+	// offline mode keeps using the cached commit, while online the package is downloaded again
+	_, offlineRevision, err := NewCachedFilesGetter(NewCache(cachePath), true).GetFilesForPackage(pkg)
+	require.NoError(t, err)
+	assert.Equal(t, revision, offlineRevision)
+
+	files, newRevision, err := NewCachedFilesGetter(NewCache(cachePath), false).GetFilesForPackage(pkg)
+	require.NoError(t, err)
+	assert.Equal(t, hash.String(), newRevision.Commit)
+	require.Len(t, files, 1)
+	targetPath := t.TempDir()
+	require.NoError(t, files[0].WriteContent(targetPath))
+	content, err := os.ReadFile(filepath.Join(targetPath, "file1.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "new content\n", string(content))
+}
+
+func TestCacheKeepsFileMode(t *testing.T) {
+	t.Parallel()
+
+	fsys := memfs.New()
+	file, err := fsys.OpenFile("script.sh", os.O_WRONLY|os.O_CREATE, 0755)
+	require.NoError(t, err)
+	_, err = file.Write([]byte("#!/bin/sh\n"))
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	cachePath := t.TempDir()
+	_, _, err = NewCache(cachePath).store("key", fsys, TestCommit)
+	require.NoError(t, err)
+	info, err := os.Stat(filepath.Join(cachePath, "key", cacheFilesDirName, "script.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+}
+
+func TestKeyForPackage(t *testing.T) {
+	t.Parallel()
+
+	pkg := v1alpha1.NewAddon(t, "category/test-addon", "1.0.0", false)
+	otherVersion := v1alpha1.NewAddon(t, "category/test-addon", "1.0.1", false)
+	otherSource := v1alpha1.NewAddon(t, "category/test-addon", "1.0.0", false)
+	otherSource.Source = v1alpha1.Source{URL: "https://example.com/repo.git"}
+
+	assert.Equal(t, keyForPackage(pkg), keyForPackage(v1alpha1.NewAddon(t, "category/test-addon", "1.0.0", false)))
+	assert.NotEqual(t, keyForPackage(pkg), keyForPackage(otherVersion))
+	assert.NotEqual(t, keyForPackage(pkg), keyForPackage(otherSource))
+}
@@ -17,8 +17,14 @@ package git
 
 import (
 	"bufio"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
 
 	"github.com/go-git/go-billy/v5"
 )
@@ -57,3 +63,32 @@ func (f *File) WriteContent(targetPath string) error {
 	_, err = r.WriteTo(w)
 	return err
 }
+
+// hashPrefix is the prefix of the hashes returned by HashFiles, for identifying the algorithm used
+const hashPrefix = "sha256:"
+
+// HashFiles return an hash of the relative paths and the contents of files that does not depend on their order
+func HashFiles(files []*File) (string, error) {
+	sortedFiles := slices.SortedFunc(slices.Values(files), func(a, b *File) int {
+		return cmp.Compare(a.path, b.path)
+	})
+
+	hash := sha256.New()
+	for _, f := range sortedFiles {
+		file, err := f.fs.Open(f.internalPath)
+		if err != nil {
+			return "", err
+		}
+
+		fileHash := sha256.New()
+		_, err = io.Copy(fileHash, file)
+		file.Close()
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(hash, "%s  %s\n", hex.EncodeToString(fileHash.Sum(nil)), filepath.ToSlash(f.path))
+	}
+
+	return hashPrefix + hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -82,3 +82,29 @@ func TestWriteContent(t *testing.T) {
 		})
 	}
 }
+
+func TestHashFiles(t *testing.T) {
+	t.Parallel()
+
+	fs := memfs.New()
+	populateWorktree(t, fs)
+	files := []*File{
+		{path: "file1.yaml", internalPath: "modules/category/test-module1/test-flavor1/file1.yaml", fs: fs},
+		{path: "file2.yaml", internalPath: "modules/category/test-module1/test-flavor1/file2.yaml", fs: fs},
+	}
+
+	hash, err := HashFiles(files)
+	require.NoError(t, err)
+	assert.Regexp(t, "^sha256:[0-9a-f]{64}$", hash)
+
+	reversedHash, err := HashFiles([]*File{files[1], files[0]})
+	require.NoError(t, err)
+	assert.Equal(t, hash, reversedHash)
+
+	renamedHash, err := HashFiles([]*File{files[0], {path: "renamed.yaml", internalPath: files[1].internalPath, fs: fs}})
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, renamedHash)
+
+	_, err = HashFiles([]*File{{path: "missing.yaml", internalPath: "missing.yaml", fs: fs}})
+	assert.Error(t, err)
+}
@@ -16,6 +16,7 @@
 package git
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
 	"path/filepath"
@@ -25,8 +26,8 @@ import (
 	"github.com/go-git/go-billy/v5/memfs"
 	billyutil "github.com/go-git/go-billy/v5/util"
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
-	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/storage/memory"
 
 	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
@@ -37,68 +38,292 @@ const (
 	defaultGitURL = "https://github.com"
 	// defaultRepositoryUrl is the default repository to use if no other is specified
 	defaultRepositoryURL = defaultGitURL + "/mia-platform/distribution"
+	// defaultTagScheme is the tag scheme to use if no other is specified
+	defaultTagScheme = typePlaceholder + "-" + namePlaceholder + "-" + versionPlaceholder
+
+	typePlaceholder    = "{type}"
+	namePlaceholder    = "{name}"
+	versionPlaceholder = "{version}"
+
+	// peeledSuffix is the suffix of the references pointing to the objects referenced by annotated tags
+	peeledSuffix = "^{}"
+)
+
+var (
+	// ErrTagNotFound is returned when the tag of a package version is not present in its repository
+	ErrTagNotFound = errors.New("tag not found")
+	// ErrPackageNotFound is returned when the folder of a package is not present at the tag of its version
+	ErrPackageNotFound = errors.New("package not found")
+	// ErrFlavorNotFound is returned when the folder of a module flavor is not present inside the module folder
+	ErrFlavorNotFound = errors.New("flavor not found")
 )
 
 // remoteUrl return the git url to use for downloading the files for a package (module or addon)
-func remoteURL() string {
+func remoteURL(pkg v1alpha1.Package) string {
+	if len(pkg.Source.URL) > 0 {
+		return pkg.Source.URL
+	}
 	return defaultRepositoryURL
 }
 
-// remoteAuth return an AuthMethod for package (module or addon)
-func remoteAuth() transport.AuthMethod {
-	return nil
+// SourceLocation return the repository url and the folder inside it from where the files of pkg are downloaded
+func SourceLocation(pkg v1alpha1.Package) string {
+	if len(pkg.Source.Path) > 0 {
+		return remoteURL(pkg) + "//" + pkg.Source.Path
+	}
+	return remoteURL(pkg)
+}
+
+// tagPatternForPackage return the tag scheme of pkg with the type and name placeholders replaced,
+// leaving only the version placeholder
+func tagPatternForPackage(pkg v1alpha1.Package) string {
+	tagScheme := pkg.Source.TagScheme
+	if len(tagScheme) == 0 {
+		tagScheme = defaultTagScheme
+	}
+
+	replacer := strings.NewReplacer(
+		typePlaceholder, pkg.PackageType(),
+		namePlaceholder, strings.ReplaceAll(pkg.GetName(), "/", "-"),
+	)
+	return replacer.Replace(tagScheme)
 }
 
 // tagReferenceForPackage return a valid tag reference for the package name and version
 func tagReferenceForPackage(pkg v1alpha1.Package) plumbing.ReferenceName {
-	tag := pkg.PackageType() + "-" + strings.ReplaceAll(pkg.GetName(), "/", "-") + "-" + pkg.Version
+	tag := strings.ReplaceAll(tagPatternForPackage(pkg), versionPlaceholder, pkg.Version)
 	return plumbing.NewTagReferenceName(tag)
 }
 
+// versionsFromReferences return the versions of pkg contained in the names of the tags found in refs
+func versionsFromReferences(pkg v1alpha1.Package, refs []*plumbing.Reference) []string {
+	prefix, suffix, found := strings.Cut(tagPatternForPackage(pkg), versionPlaceholder)
+	if !found {
+		return nil
+	}
+
+	versions := make([]string, 0)
+	for _, ref := range refs {
+		name := ref.Name()
+		if !name.IsTag() || strings.HasSuffix(name.String(), peeledSuffix) {
+			continue
+		}
+
+		tag := name.Short()
+		if len(tag) <= len(prefix)+len(suffix) || !strings.HasPrefix(tag, prefix) || !strings.HasSuffix(tag, suffix) {
+			continue
+		}
+		versions = append(versions, tag[len(prefix):len(tag)-len(suffix)])
+	}
+
+	return versions
+}
+
+// packageFolderPath return the path of the folder containing the pkg files inside its repository
+func packageFolderPath(pkg v1alpha1.Package) string {
+	return filepath.Join(pkg.Source.Path, pkg.PackageType()+"s", pkg.GetName())
+}
+
 // cloneOptionsForPackage return the options for cloning the package with pkgName with pkg configuaration
-func cloneOptionsForPackage(pkg v1alpha1.Package) *git.CloneOptions {
+func cloneOptionsForPackage(pkg v1alpha1.Package) (*git.CloneOptions, error) {
+	url := remoteURL(pkg)
+	auth, err := remoteAuth(url, pkg.Source.Credentials)
+	if err != nil {
+		return nil, err
+	}
+
 	return &git.CloneOptions{
-		URL:           remoteURL(),
-		Auth:          remoteAuth(),
+		URL:           url,
+		Auth:          auth,
 		ReferenceName: tagReferenceForPackage(pkg),
 		Depth:         1,
 		SingleBranch:  true,
 		Tags:          git.NoTags,
+	}, nil
+}
+
+// Revision contains the repository, tag and commit from which the files of a package are downloaded
+type Revision struct {
+	Repository string
+	Tag        string
+	Commit     string
+}
+
+// revisionForPackage return the revision of pkg with commit
+func revisionForPackage(pkg v1alpha1.Package, commit string) Revision {
+	return Revision{
+		Repository: remoteURL(pkg),
+		Tag:        tagReferenceForPackage(pkg).Short(),
+		Commit:     commit,
 	}
 }
 
 // FilesGetter is responsible to download and manage remote git repository in a in memory storage
+// or in an on disk cache
 type FilesGetter struct {
-	clonePackage func(v1alpha1.Package) (billy.Filesystem, error)
+	clonePackage  func(v1alpha1.Package) (billy.Filesystem, string, error)
+	resolveCommit func(v1alpha1.Package) (string, error)
+	listVersions  func(v1alpha1.Package) ([]string, error)
 }
 
 // NewFilesGetter create a new FilesGetter instance configured for downloading from remote repository using
 // an in memory storage
 func NewFilesGetter() *FilesGetter {
 	return &FilesGetter{
-		clonePackage: func(pkg v1alpha1.Package) (billy.Filesystem, error) {
-			fs := memfs.New()
-			storage := memory.NewStorage()
-			cloneOptions := cloneOptionsForPackage(pkg)
-			if _, err := git.Clone(storage, fs, cloneOptions); err != nil {
-				return nil, fmt.Errorf("error cloning repository %w", err)
+		clonePackage:  clonePackageInMemory,
+		resolveCommit: resolveRemoteCommit,
+		listVersions:  listRemoteVersions,
+	}
+}
+
+// NewCachedFilesGetter create a new FilesGetter instance that reuses the repositories already stored in cache,
+// and stores there the ones downloaded from remote. A cached repository is reused only if the tag of the package
+// still points to the cached commit, otherwise it is downloaded again. If offline is true, no remote repository is
+// contacted, the cached repositories are always reused and a package not found in cache will return an
+// ErrPackageNotCached error
+func NewCachedFilesGetter(cache *Cache, offline bool) *FilesGetter {
+	return &FilesGetter{
+		clonePackage: func(pkg v1alpha1.Package) (billy.Filesystem, string, error) {
+			key := keyForPackage(pkg)
+			fs, commit, found := cache.get(key)
+			switch {
+			case found && offline:
+				return fs, commit, nil
+			case offline:
+				return nil, "", notCachedError(pkg)
+			case found:
+				remoteCommit, err := resolveRemoteCommit(pkg)
+				if err != nil {
+					return nil, "", err
+				}
+				if remoteCommit == commit {
+					return fs, commit, nil
+				}
+
+				// the tag has been moved to another commit after the repository was cached
+				if err := cache.remove(key); err != nil {
+					return nil, "", err
+				}
+			}
+
+			fs, commit, err := clonePackageInMemory(pkg)
+			if err != nil {
+				return nil, "", err
+			}
+
+			return cache.store(key, fs, commit)
+		},
+		resolveCommit: func(pkg v1alpha1.Package) (string, error) {
+			if offline {
+				if _, commit, found := cache.get(keyForPackage(pkg)); found {
+					return commit, nil
+				}
+				return "", notCachedError(pkg)
 			}
 
-			return fs, nil
+			return resolveRemoteCommit(pkg)
+		},
+		listVersions: func(pkg v1alpha1.Package) ([]string, error) {
+			if offline {
+				return nil, fmt.Errorf("%w: cannot list the versions of %s %s", ErrOffline, pkg.PackageType(), pkg.GetName())
+			}
+
+			return listRemoteVersions(pkg)
 		},
 	}
 }
 
-// GetFilesForPackage clones the pkg from the remote repository and return all the files relative for the package
-// or an error otherwise
-func (r *FilesGetter) GetFilesForPackage(pkg v1alpha1.Package) ([]*File, error) {
-	memFs, err := r.clonePackage(pkg)
+// notCachedError return an ErrPackageNotCached error for pkg
+func notCachedError(pkg v1alpha1.Package) error {
+	return fmt.Errorf("%w: %s from %s", ErrPackageNotCached, tagReferenceForPackage(pkg).Short(), remoteURL(pkg))
+}
+
+// clonePackageInMemory clones the repository of pkg at its tag in an in memory storage, and return it
+// with the hash of the cloned commit
+func clonePackageInMemory(pkg v1alpha1.Package) (billy.Filesystem, string, error) {
+	fs := memfs.New()
+	storage := memory.NewStorage()
+	cloneOptions, err := cloneOptionsForPackage(pkg)
+	if err != nil {
+		return nil, "", err
+	}
+
+	repository, err := git.Clone(storage, fs, cloneOptions)
+	if err != nil {
+		return nil, "", fmt.Errorf("error cloning repository %w", err)
+	}
+
+	commit, err := repository.ResolveRevision(plumbing.Revision(cloneOptions.ReferenceName.String() + "^{commit}"))
+	if err != nil {
+		return nil, "", fmt.Errorf("error resolving commit %w", err)
+	}
+
+	return fs, commit.String(), nil
+}
+
+// listRemoteReferences return the references found in the remote repository of pkg, without cloning it
+func listRemoteReferences(pkg v1alpha1.Package) ([]*plumbing.Reference, error) {
+	url := remoteURL(pkg)
+	auth, err := remoteAuth(url, pkg.Source.Credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{Name: git.DefaultRemoteName, URLs: []string{url}})
+	refs, err := remote.List(&git.ListOptions{Auth: auth, PeelingOption: git.AppendPeeled})
+	if err != nil {
+		return nil, fmt.Errorf("error listing remote references %w", err)
+	}
+
+	return refs, nil
+}
+
+// listRemoteVersions return the versions of pkg available in its remote repository
+func listRemoteVersions(pkg v1alpha1.Package) ([]string, error) {
+	refs, err := listRemoteReferences(pkg)
 	if err != nil {
 		return nil, err
 	}
 
+	return versionsFromReferences(pkg, refs), nil
+}
+
+// resolveRemoteCommit return the hash of the commit pointed by the tag of pkg in its remote repository,
+// without cloning it
+func resolveRemoteCommit(pkg v1alpha1.Package) (string, error) {
+	refs, err := listRemoteReferences(pkg)
+	if err != nil {
+		return "", err
+	}
+
+	tag := tagReferenceForPackage(pkg)
+	commit := ""
+	for _, ref := range refs {
+		switch ref.Name() {
+		case tag + peeledSuffix:
+			// annotated tags are peeled to the commit they point to
+			return ref.Hash().String(), nil
+		case tag:
+			commit = ref.Hash().String()
+		}
+	}
+
+	if len(commit) == 0 {
+		return "", fmt.Errorf("%w: %s in %s", ErrTagNotFound, tag.Short(), remoteURL(pkg))
+	}
+	return commit, nil
+}
+
+// GetFilesForPackage clones the pkg from its source repository and return all the files relative for the package
+// and the revision they come from, or an error otherwise
+func (r *FilesGetter) GetFilesForPackage(pkg v1alpha1.Package) ([]*File, Revision, error) {
+	memFs, commit, err := r.clonePackage(pkg)
+	if err != nil {
+		return nil, Revision{}, err
+	}
+
 	var files []*File
-	packageFolder := filepath.Join(pkg.PackageType()+"s", pkg.GetName())
+	packageFolder := packageFolderPath(pkg)
 	err = billyutil.Walk(memFs, packageFolder, func(filePath string, info fs.FileInfo, err error) error {
 		if err != nil || info.IsDir() {
 			return err
@@ -109,6 +334,57 @@ func (r *FilesGetter) GetFilesForPackage(pkg v1alpha1.Package) ([]*File, error)
 		files = append(files, &File{path: relativePath, internalPath: filePath, fs: memFs})
 		return nil
 	})
+	if err != nil {
+		return nil, Revision{}, err
+	}
+
+	return files, revisionForPackage(pkg, commit), nil
+}
+
+// RemoteRevision return the revision currently pointed by the tag of pkg in its repository
+func (r *FilesGetter) RemoteRevision(pkg v1alpha1.Package) (Revision, error) {
+	commit, err := r.resolveCommit(pkg)
+	if err != nil {
+		return Revision{}, err
+	}
+
+	return revisionForPackage(pkg, commit), nil
+}
+
+// ListVersions return all the versions of pkg found in the tags of its repository
+func (r *FilesGetter) ListVersions(pkg v1alpha1.Package) ([]string, error) {
+	return r.listVersions(pkg)
+}
+
+// CheckPackage verifies that the tag of pkg exists in its repository and that it contains the folder of pkg, and
+// the folder of its flavor for the modules. The missing items are reported with the ErrTagNotFound,
+// ErrPackageNotFound and ErrFlavorNotFound errors
+func (r *FilesGetter) CheckPackage(pkg v1alpha1.Package) error {
+	if _, err := r.resolveCommit(pkg); err != nil {
+		return err
+	}
+
+	fsys, _, err := r.clonePackage(pkg)
+	if err != nil {
+		return err
+	}
+
+	tag := tagReferenceForPackage(pkg).Short()
+	packageFolder := packageFolderPath(pkg)
+	if !isDir(fsys, packageFolder) {
+		return fmt.Errorf("%w: missing folder %s in tag %s", ErrPackageNotFound, packageFolder, tag)
+	}
+
+	flavorFolder := filepath.Join(packageFolder, pkg.GetFlavorName())
+	if pkg.IsModule() && !isDir(fsys, flavorFolder) {
+		return fmt.Errorf("%w: missing folder %s in tag %s", ErrFlavorNotFound, flavorFolder, tag)
+	}
+
+	return nil
+}
 
-	return files, err
+// isDir return true if path exists in fsys and it is a directory
+func isDir(fsys billy.Filesystem, path string) bool {
+	info, err := fsys.Stat(path)
+	return err == nil && info.IsDir()
 }
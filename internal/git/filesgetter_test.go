@@ -17,17 +17,22 @@ package git
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
 )
 
 func TestCloneOptions(t *testing.T) {
-	t.Parallel()
+	t.Setenv(netrcEnvName, filepath.Join(t.TempDir(), "missing"))
 
 	defaultGitURL := "https://github.com/mia-platform/distribution"
 	tests := map[string]struct {
@@ -48,13 +53,22 @@ func TestCloneOptions(t *testing.T) {
 			expectedAuth:      nil,
 			expectedReference: plumbing.NewTagReferenceName("addon-category-addon-name-1.0.0"),
 		},
+		"package with custom source": {
+			pkgDefinition: func() v1alpha1.Package {
+				pkg := v1alpha1.NewModule(t, "category/module-name/flavor-name", "1.0.0", false)
+				pkg.Source = v1alpha1.Source{URL: "https://example.com/repo.git", TagScheme: "{name}/v{version}"}
+				return pkg
+			}(),
+			expectedURL:       "https://example.com/repo.git",
+			expectedAuth:      nil,
+			expectedReference: plumbing.NewTagReferenceName("category-module-name/v1.0.0"),
+		},
 	}
 
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
-			t.Parallel()
-
-			options := cloneOptionsForPackage(test.pkgDefinition)
+			options, err := cloneOptionsForPackage(test.pkgDefinition)
+			require.NoError(t, err)
 			assert.Equal(t, test.expectedURL, options.URL)
 			assert.Equal(t, test.expectedAuth, options.Auth)
 			assert.Equal(t, test.expectedReference, options.ReferenceName)
@@ -120,11 +134,12 @@ func TestGetFiles(t *testing.T) {
 				file.fs = fs
 			}
 
-			files, err := fg.GetFilesForPackage(test.pkgDefinition)
+			files, revision, err := fg.GetFilesForPackage(test.pkgDefinition)
 			switch len(test.expectedError) {
 			case 0:
 				assert.NoError(t, err)
 				assert.Equal(t, test.expectedFiles, files)
+				assert.Equal(t, TestCommit, revision.Commit)
 			default:
 				assert.ErrorContains(t, err, test.expectedError)
 				assert.Nil(t, files)
@@ -132,3 +147,167 @@ func TestGetFiles(t *testing.T) {
 		})
 	}
 }
+
+func TestGetFilesFromLocalRepository(t *testing.T) {
+	t.Parallel()
+
+	filePath := filepath.Join("distribution", "addons", "category", "test-addon", "file1.yaml")
+	repoPath := newLocalRepository(t, "test-addon-1.0.0", filePath)
+
+	pkg := v1alpha1.NewAddon(t, "category/test-addon", "1.0.0", false)
+	pkg.Source = v1alpha1.Source{
+		URL:       "file://" + repoPath,
+		Path:      "distribution",
+		TagScheme: "test-addon-{version}",
+	}
+
+	files, revision, err := NewFilesGetter().GetFilesForPackage(pkg)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "file1.yaml", files[0].path)
+	assert.Equal(t, filePath, files[0].internalPath)
+
+	remoteRevision, err := NewFilesGetter().RemoteRevision(pkg)
+	require.NoError(t, err)
+	assert.Equal(t, revision, remoteRevision)
+	assert.Equal(t, Revision{Repository: pkg.Source.URL, Tag: "test-addon-1.0.0", Commit: revision.Commit}, revision)
+}
+
+func TestListVersions(t *testing.T) {
+	t.Parallel()
+
+	repoPath := newLocalRepository(t, "addon-category-test-addon-1.0.0", filepath.Join("addons", "category", "test-addon", "file1.yaml"))
+	repo, err := git.PlainOpen(repoPath)
+	require.NoError(t, err)
+	head, err := repo.Head()
+	require.NoError(t, err)
+	_, err = repo.CreateTag("addon-category-test-addon-1.1.0", head.Hash(), &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		Message: "annotated tag",
+	})
+	require.NoError(t, err)
+	_, err = repo.CreateTag("addon-category-other-addon-2.0.0", head.Hash(), nil)
+	require.NoError(t, err)
+
+	pkg := v1alpha1.NewAddon(t, "category/test-addon", "^1.0.0", false)
+	pkg.Source = v1alpha1.Source{URL: "file://" + repoPath}
+
+	versions, err := NewFilesGetter().ListVersions(pkg)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"1.0.0", "1.1.0"}, versions)
+}
+
+func TestCheckPackage(t *testing.T) {
+	t.Parallel()
+
+	repoPath := newLocalRepository(t, "module-category-test-module-1.0.0", filepath.Join("modules", "category", "test-module", "flavor", "file1.yaml"))
+	repoURL := "file://" + repoPath
+	tests := map[string]struct {
+		pkg           v1alpha1.Package
+		expectedError error
+	}{
+		"existing package": {
+			pkg: v1alpha1.NewModule(t, "category/test-module/flavor", "1.0.0", false),
+		},
+		"missing tag": {
+			pkg:           v1alpha1.NewModule(t, "category/test-module/flavor", "2.0.0", false),
+			expectedError: ErrTagNotFound,
+		},
+		"missing package": {
+			pkg:           v1alpha1.NewModule(t, "category/other-module/flavor", "1.0.0", false),
+			expectedError: ErrTagNotFound,
+		},
+		"missing flavor": {
+			pkg:           v1alpha1.NewModule(t, "category/test-module/other-flavor", "1.0.0", false),
+			expectedError: ErrFlavorNotFound,
+		},
+		"missing package folder": {
+			pkg: func() v1alpha1.Package {
+				pkg := v1alpha1.NewModule(t, "category/test-module/flavor", "1.0.0", false)
+				pkg.Source.Path = "distribution"
+				return pkg
+			}(),
+			expectedError: ErrPackageNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			pkg := test.pkg
+			pkg.Source.URL = repoURL
+			err := NewFilesGetter().CheckPackage(pkg)
+			if test.expectedError == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, test.expectedError)
+		})
+	}
+}
+
+func TestVersionsFromReferences(t *testing.T) {
+	t.Parallel()
+
+	refs := []*plumbing.Reference{
+		plumbing.NewHashReference(plumbing.NewBranchReferenceName("main"), plumbing.ZeroHash),
+		plumbing.NewHashReference(plumbing.NewTagReferenceName("module-category-test-module-1.0.0"), plumbing.ZeroHash),
+		plumbing.NewHashReference(plumbing.NewTagReferenceName("module-category-test-module-1.0.0^{}"), plumbing.ZeroHash),
+		plumbing.NewHashReference(plumbing.NewTagReferenceName("module-category-test-module-"), plumbing.ZeroHash),
+		plumbing.NewHashReference(plumbing.NewTagReferenceName("module-category-test-module-2.0.0"), plumbing.ZeroHash),
+		plumbing.NewHashReference(plumbing.NewTagReferenceName("addon-category-test-module-3.0.0"), plumbing.ZeroHash),
+		plumbing.NewHashReference(plumbing.NewTagReferenceName("v4.0.0-category-test-module"), plumbing.ZeroHash),
+	}
+
+	tests := map[string]struct {
+		tagScheme        string
+		expectedVersions []string
+	}{
+		"default tag scheme": {
+			expectedVersions: []string{"1.0.0", "2.0.0"},
+		},
+		"version before the name": {
+			tagScheme:        "v{version}-{name}",
+			expectedVersions: []string{"4.0.0"},
+		},
+		"tag scheme without version": {
+			tagScheme: "{type}-{name}",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			pkg := v1alpha1.NewModule(t, "category/test-module/flavor", "^1.0.0", false)
+			pkg.Source.TagScheme = test.tagScheme
+			assert.Equal(t, test.expectedVersions, versionsFromReferences(pkg, refs))
+		})
+	}
+}
+
+// newLocalRepository create a git repository on disk containing filePath and with a tag pointing to
+// its only commit, and return its path
+func newLocalRepository(t *testing.T, tag, filePath string) string {
+	t.Helper()
+
+	repoPath := t.TempDir()
+	repo, err := git.PlainInit(repoPath, false)
+	require.NoError(t, err)
+	worktree, err := repo.Worktree()
+	require.NoError(t, err)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(repoPath, filepath.Dir(filePath)), os.ModePerm))
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, filePath), []byte("content\n"), 0600))
+	_, err = worktree.Add(".")
+	require.NoError(t, err)
+	hash, err := worktree.Commit("add "+filePath, &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+	_, err = repo.CreateTag(tag, hash, nil)
+	require.NoError(t, err)
+
+	return repoPath
+}
@@ -26,17 +26,27 @@ import (
 	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
 )
 
+// TestCommit is the commit returned for every package by the FilesGetter created by NewTestFilesGetter
+const TestCommit = "0123456789abcdef0123456789abcdef01234567"
+
+// TestVersions contains the versions returned for every package by the FilesGetter created by NewTestFilesGetter
+var TestVersions = []string{"v1.0.0", "v1.0.1", "v1.1.0", "v2.0.0-rc.1", "v2.0.0"}
+
 // NewTestFilesGetter return a FilesGetter with a fixed worktree and will not make calls to remote repositories
 func NewTestFilesGetter(t *testing.T) (*FilesGetter, billy.Filesystem) {
 	t.Helper()
 
 	fg := NewFilesGetter()
 	f := memfs.New()
-	fg.clonePackage = func(_ v1alpha1.Package) (billy.Filesystem, error) {
-		t.Helper()
-
-		populateWorktree(t, f)
-		return f, nil
+	populateWorktree(t, f)
+	fg.clonePackage = func(_ v1alpha1.Package) (billy.Filesystem, string, error) {
+		return f, TestCommit, nil
+	}
+	fg.resolveCommit = func(_ v1alpha1.Package) (string, error) {
+		return TestCommit, nil
+	}
+	fg.listVersions = func(_ v1alpha1.Package) ([]string, error) {
+		return TestVersions, nil
 	}
 
 	return fg, f
@@ -0,0 +1,271 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command schemagen generates the JSON Schema of a configuration type from the Go source of its package, using the
+// json tags for the properties names and the doc comments for their descriptions.
+// It is run with go generate inside the package containing the type
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	schemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+	// kindConstName and versionConstName are the constants containing the kind and apiVersion values
+	kindConstName    = "Kind"
+	versionConstName = "Version"
+
+	filePermission = 0644
+)
+
+// requiredProperties are the properties that must be present in the root object
+var requiredProperties = []string{"kind", "apiVersion", "spec"}
+
+// typeDecl contains a struct type declared in the parsed package and its doc comment
+type typeDecl struct {
+	doc    string
+	fields []*ast.Field
+}
+
+// generator builds the schema of the types declared in a package
+type generator struct {
+	types     map[string]typeDecl
+	constants map[string]string
+	defs      map[string]any
+}
+
+func main() {
+	typeName := flag.String("type", "", "name of the type to generate the schema for")
+	id := flag.String("id", "", "$id of the generated schema")
+	output := flag.String("output", "", "path of the generated schema file")
+	flag.Parse()
+
+	if err := run(".", *typeName, *id, *output); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run writes at output the schema of typeName declared in the package contained in dir
+func run(dir, typeName, id, output string) error {
+	if len(typeName) == 0 || len(output) == 0 {
+		return errors.New("the type and output flags are required")
+	}
+
+	data, err := generate(dir, typeName, id)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(output, data, filePermission)
+}
+
+// generate return the schema of typeName declared in the package contained in dir
+func generate(dir, typeName, id string) ([]byte, error) {
+	g, err := parsePackage(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	decl, found := g.types[typeName]
+	if !found {
+		return nil, fmt.Errorf("type %s not found in %s", typeName, dir)
+	}
+
+	root := g.objectSchema(decl)
+	properties := root["properties"].(map[string]any)
+	for property, constName := range map[string]string{"kind": kindConstName, "apiVersion": versionConstName} {
+		value, found := g.constants[constName]
+		if !found {
+			return nil, fmt.Errorf("constant %s not found in %s", constName, dir)
+		}
+		properties[property].(map[string]any)["const"] = value
+	}
+	root["required"] = requiredProperties
+	root["$schema"] = schemaDraft
+	root["$id"] = id
+	root["title"] = typeName
+	root["$defs"] = g.defs
+
+	buffer := new(bytes.Buffer)
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(root); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// parsePackage collects the struct types and the string constants declared in the non test files of dir
+func parsePackage(dir string) (*generator, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{
+		types:     make(map[string]typeDecl),
+		constants: make(map[string]string),
+		defs:      make(map[string]any),
+	}
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+
+		parsed, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, decl := range parsed.Decls {
+			if genDecl, ok := decl.(*ast.GenDecl); ok {
+				g.collect(genDecl)
+			}
+		}
+	}
+
+	return g, nil
+}
+
+// collect records the struct types and string constants declared in decl
+func (g *generator) collect(decl *ast.GenDecl) {
+	for _, spec := range decl.Specs {
+		switch spec := spec.(type) {
+		case *ast.TypeSpec:
+			structType, ok := spec.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+
+			doc := spec.Doc
+			if doc == nil {
+				doc = decl.Doc
+			}
+			g.types[spec.Name.Name] = typeDecl{doc: commentText(doc), fields: structType.Fields.List}
+		case *ast.ValueSpec:
+			for idx, name := range spec.Names {
+				if idx >= len(spec.Values) {
+					continue
+				}
+				if lit, ok := spec.Values[idx].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+					value, _ := strconv.Unquote(lit.Value)
+					g.constants[name.Name] = value
+				}
+			}
+		}
+	}
+}
+
+// objectSchema return the schema of the object described by decl, the fields embedded with the inline option
+// are merged in its properties
+func (g *generator) objectSchema(decl typeDecl) map[string]any {
+	properties := make(map[string]any)
+	for _, field := range decl.fields {
+		name, inline := jsonName(field)
+		switch {
+		case inline:
+			if embedded, ok := field.Type.(*ast.Ident); ok {
+				maps.Copy(properties, g.objectSchema(g.types[embedded.Name])["properties"].(map[string]any))
+			}
+		case len(name) > 0:
+			schema := g.typeSchema(field.Type)
+			if doc := commentText(field.Doc); len(doc) > 0 {
+				schema["description"] = doc
+			}
+			properties[name] = schema
+		}
+	}
+
+	schema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(decl.doc) > 0 {
+		schema["description"] = decl.doc
+	}
+	return schema
+}
+
+// typeSchema return the schema of expr, the struct types are added to the definitions and referenced
+func (g *generator) typeSchema(expr ast.Expr) map[string]any {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		switch expr.Name {
+		case "string":
+			return map[string]any{"type": "string"}
+		case "bool":
+			return map[string]any{"type": "boolean"}
+		case "int", "int32", "int64":
+			return map[string]any{"type": "integer"}
+		}
+
+		if _, found := g.defs[expr.Name]; !found {
+			// set a placeholder before visiting the type for supporting recursive types
+			g.defs[expr.Name] = nil
+			g.defs[expr.Name] = g.objectSchema(g.types[expr.Name])
+		}
+		return map[string]any{"$ref": "#/$defs/" + expr.Name}
+	case *ast.MapType:
+		return map[string]any{"type": "object", "additionalProperties": g.typeSchema(expr.Value)}
+	case *ast.ArrayType:
+		return map[string]any{"type": "array", "items": g.typeSchema(expr.Elt)}
+	case *ast.StarExpr:
+		return g.typeSchema(expr.X)
+	default:
+		return map[string]any{}
+	}
+}
+
+// jsonName return the name of the property of field from its json tag, and if the field is inlined in its parent.
+// The name is empty for the fields not serialized
+func jsonName(field *ast.Field) (string, bool) {
+	if field.Tag == nil || len(field.Names) > 1 {
+		return "", false
+	}
+
+	tag, _ := strconv.Unquote(field.Tag.Value)
+	name, options, _ := strings.Cut(reflect.StructTag(tag).Get("json"), ",")
+	if len(field.Names) == 0 {
+		return "", strings.Contains(options, "inline")
+	}
+	if name == "-" || !field.Names[0].IsExported() {
+		return "", false
+	}
+	return name, false
+}
+
+// commentText return the text of comment with its lines joined by newlines
+func commentText(comment *ast.CommentGroup) string {
+	return strings.TrimSpace(comment.Text())
+}
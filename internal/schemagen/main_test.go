@@ -0,0 +1,86 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	apisPath     = "../../pkg/apis/vab.mia-platform.eu/v1alpha1"
+	schemaFile   = "clustersconfiguration.schema.json"
+	schemaPrefix = "https://raw.githubusercontent.com/mia-platform/vab/main/pkg/apis/vab.mia-platform.eu/"
+)
+
+func TestGeneratedSchemaIsUpToDate(t *testing.T) {
+	t.Parallel()
+
+	for _, version := range []string{"v1alpha1", "v1alpha2"} {
+		t.Run(version, func(t *testing.T) {
+			t.Parallel()
+
+			dir := filepath.Join("../../pkg/apis/vab.mia-platform.eu", version)
+			expected, err := os.ReadFile(filepath.Join(dir, schemaFile))
+			require.NoError(t, err)
+
+			data, err := generate(dir, "ClustersConfiguration", schemaPrefix+version+"/"+schemaFile)
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), string(data), "the schema is outdated, run make generate for updating it")
+		})
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		dir           string
+		typeName      string
+		output        string
+		expectedError string
+	}{
+		"missing flags": {
+			dir:           apisPath,
+			expectedError: "the type and output flags are required",
+		},
+		"missing type": {
+			dir:           apisPath,
+			typeName:      "MissingType",
+			output:        filepath.Join(t.TempDir(), "schema.json"),
+			expectedError: "type MissingType not found in " + apisPath,
+		},
+		"missing constants": {
+			dir:           "testdata",
+			typeName:      "Config",
+			output:        filepath.Join(t.TempDir(), "schema.json"),
+			expectedError: "constant Kind not found in testdata",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := run(test.dir, test.typeName, schemaPrefix+"v1alpha1/"+schemaFile, test.output)
+			assert.EqualError(t, err, test.expectedError)
+		})
+	}
+}
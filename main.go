@@ -17,13 +17,20 @@ package main
 
 import (
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/mia-platform/vab/pkg/cmd"
 )
 
 func main() {
 	rootCmd := cmd.NewVabCommand()
-	if err := rootCmd.Execute(); err != nil {
+
+	// cancel the command context on interrupt so long running operations can stop cleanly
+	ctx, stop := signal.NotifyContext(rootCmd.Context(), os.Interrupt, syscall.SIGTERM)
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
+	if err != nil {
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,25 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import _ "embed"
+
+//go:generate go run ../../../../internal/schemagen -type ClustersConfiguration -id https://raw.githubusercontent.com/mia-platform/vab/main/pkg/apis/vab.mia-platform.eu/v1alpha1/clustersconfiguration.schema.json -output clustersconfiguration.schema.json
+
+// ClustersConfigurationSchema contains the JSON Schema of the ClustersConfiguration, generated from its types
+//
+//go:embed clustersconfiguration.schema.json
+var ClustersConfigurationSchema []byte
@@ -15,6 +15,8 @@
 
 package v1alpha1
 
+import "maps"
+
 // TypeMeta partially copies apimachinery/pkg/apis/meta/v1.TypeMeta
 type TypeMeta struct {
 	Kind       string `json:"kind,omitempty" yaml:"kind,omitempty"`
@@ -50,6 +52,10 @@ type ConfigSpec struct {
 	// AddOns in the dictionary are referenced by their name
 	AddOns map[string]Package `json:"addOns" yaml:"addOns"`
 
+	// Source of the packages that don't specify their own
+	// If not set the packages are downloaded from the Mia-Platform distribution repository
+	Source Source `json:"source,omitempty" yaml:"source,omitempty"`
+
 	// Groups contains the list of cluster groups
 	Groups []Group `json:"groups" yaml:"groups"`
 }
@@ -60,6 +66,23 @@ type Group struct {
 	// The group name
 	Name string `json:"name,omitempty" yaml:"name,omitempty"`
 
+	// Labels contains arbitrary key/value pairs used for selecting clusters
+	// These labels are inherited by every cluster of the group
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+
+	// Dictionary of Modules
+	// This field can be used to add a new module
+	// or patch/disable a default module for every cluster of the group
+	// Modules in the dictionary are referenced by "module-name/flavor-name"
+	// For example: ingress/traefik, cni/cilium, etc.
+	Modules map[string]Package `json:"modules,omitempty" yaml:"modules,omitempty"`
+
+	// Dictionary of AddOns
+	// This field can be used to add a new add-on
+	// or patch/disable a default add-on for every cluster of the group
+	// AddOns in the dictionary are referenced by their name
+	AddOns map[string]Package `json:"addOns,omitempty" yaml:"addOns,omitempty"`
+
 	// Clusters contains the list of the clusters in the group
 	// This field is required to reference the clusters correctly
 	// in the directory structure
@@ -77,6 +100,10 @@ type Cluster struct {
 	// Name of the context used by the cluster
 	Context string `json:"context,omitempty" yaml:"context,omitempty"`
 
+	// Labels contains arbitrary key/value pairs used for selecting clusters
+	// They are merged with the labels of the group, overriding them in case of conflicts
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+
 	// Dictionary of Modules
 	// This field can be used to add a new module
 	// or patch/disable a default module
@@ -91,6 +118,14 @@ type Cluster struct {
 	AddOns map[string]Package `json:"addOns,omitempty" yaml:"addOns,omitempty"`
 }
 
+// ClusterLabels return the labels of cluster merged with the ones inherited from the group
+func (group Group) ClusterLabels(cluster Cluster) map[string]string {
+	labels := make(map[string]string, len(group.Labels)+len(cluster.Labels))
+	maps.Copy(labels, group.Labels)
+	maps.Copy(labels, cluster.Labels)
+	return labels
+}
+
 // Package contains the module's version and status
 type Package struct {
 
@@ -98,10 +133,13 @@ type Package struct {
 	name string
 
 	// Version of the module to be installed
-	Version string `json:"version" yaml:"version"`
+	Version string `json:"version,omitempty" yaml:"version,omitempty"`
 
 	// Flag that disables the add-on if set to true
-	Disable bool `json:"disable" yaml:"disable"`
+	Disable bool `json:"disable,omitempty" yaml:"disable,omitempty"`
+
+	// Source of the package, if not set the source of the configuration will be used
+	Source Source `json:"source,omitempty" yaml:"source,omitempty"`
 
 	// isModule is a private property for setting if a package is a module or an addon
 	isModule bool
@@ -110,6 +148,28 @@ type Package struct {
 	flavor string
 }
 
+// Source contains the information for downloading packages from a git repository
+type Source struct {
+
+	// URL of the git repository, it can be any url supported by git, including local paths
+	// and file:// urls
+	URL string `json:"url,omitempty" yaml:"url,omitempty"`
+
+	// Path of the folder inside the repository containing the modules and addons folders
+	// If empty the repository root will be used
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+
+	// TagScheme is the template used for building the tag of a package version
+	// It can contain the {type}, {name} and {version} placeholders, and if empty
+	// the {type}-{name}-{version} scheme will be used
+	TagScheme string `json:"tagScheme,omitempty" yaml:"tagScheme,omitempty"`
+
+	// Credentials is the name of the credentials used for connecting to the repository
+	// Their values are read from the VAB_CREDENTIALS_<NAME>_* environment variables and
+	// are never written in the configuration file
+	Credentials string `json:"credentials,omitempty" yaml:"credentials,omitempty"`
+}
+
 // IsModule return the value of the private property with the same name
 func (pkg Package) IsModule() bool {
 	return pkg.isModule
@@ -132,3 +192,63 @@ func (pkg Package) PackageType() string {
 	}
 	return "addon"
 }
+
+// PackagesLock contains the revisions of the packages resolved and vendored by the sync command
+type PackagesLock struct {
+	TypeMeta `json:",inline" yaml:",inline"`
+
+	// Packages contains the list of the locked packages
+	// ordered by type, name and version
+	Packages []LockedPackage `json:"packages" yaml:"packages"`
+
+	// Ranges contains the versions resolved for the version ranges
+	// found in the configuration, ordered by type, name, range and repository
+	Ranges []LockedRange `json:"ranges,omitempty" yaml:"ranges,omitempty"`
+}
+
+// LockedPackage contains the revision used for vendoring a package
+// and the hash of its vendored files
+type LockedPackage struct {
+
+	// Type of the package, module or addon
+	Type string `json:"type" yaml:"type"`
+
+	// Name of the package, without the flavor for modules
+	Name string `json:"name" yaml:"name"`
+
+	// Version of the package as written in the configuration, or the
+	// version resolved for it if the configuration contains a range
+	Version string `json:"version" yaml:"version"`
+
+	// Repository is the url of the repository the package is downloaded from
+	Repository string `json:"repository" yaml:"repository"`
+
+	// Tag is the name of the tag used for downloading the package
+	Tag string `json:"tag" yaml:"tag"`
+
+	// Commit is the hash of the commit pointed by the tag when the package was downloaded
+	Commit string `json:"commit" yaml:"commit"`
+
+	// Hash of the paths and contents of the vendored files
+	Hash string `json:"hash" yaml:"hash"`
+}
+
+// LockedRange contains the version resolved for a version range of a package
+type LockedRange struct {
+
+	// Type of the package, module or addon
+	Type string `json:"type" yaml:"type"`
+
+	// Name of the package, without the flavor for modules
+	Name string `json:"name" yaml:"name"`
+
+	// Range of versions as written in the configuration
+	Range string `json:"range" yaml:"range"`
+
+	// Repository is the url of the repository, followed by the path of the packages inside it if set,
+	// where the range is resolved
+	Repository string `json:"repository" yaml:"repository"`
+
+	// Version resolved for the range
+	Version string `json:"version" yaml:"version"`
+}
@@ -22,6 +22,8 @@ const (
 	Kind = "ClustersConfiguration"
 	// Version Valid value for the apiVersion property of the configuration
 	Version = "vab.mia-platform.eu/v1alpha1"
+	// LockKind Valid value for the kind property of the lock file
+	LockKind = "PackagesLock"
 )
 
 // EmptyConfig generates an empty ClustersConfiguration with provided name
@@ -40,6 +42,17 @@ func EmptyConfig(name string) *ClustersConfiguration {
 	}
 }
 
+// EmptyLock generates an empty PackagesLock
+func EmptyLock() *PackagesLock {
+	return &PackagesLock{
+		TypeMeta: TypeMeta{
+			Kind:       LockKind,
+			APIVersion: Version,
+		},
+		Packages: make([]LockedPackage, 0),
+	}
+}
+
 func NewModule(t *testing.T, name string, version string, disable bool) Package {
 	t.Helper()
 	return Package{
@@ -58,6 +58,9 @@ type shadowConfigSpec struct {
 	// AddOns in the dictionary are referenced by their name
 	AddOns map[string]Package `yaml:"addOns"`
 
+	// Source of the packages that don't specify their own
+	Source Source `yaml:"source,omitempty"`
+
 	// Groups contains the list of cluster groups
 	Groups []Group `yaml:"groups"`
 }
@@ -71,29 +74,81 @@ func (configSpec *ConfigSpec) UnmarshalYAML(value *yaml.Node) error {
 		return err
 	}
 
+	configSpec.Source = temporaryConfig.Source
 	configSpec.Groups = temporaryConfig.Groups
+	configSpec.Modules = ModulesFromConfig(temporaryConfig.Modules)
+	configSpec.AddOns = AddOnsFromConfig(temporaryConfig.AddOns)
 
-	newModules := map[string]Package{}
-	for key, module := range temporaryConfig.Modules {
-		moduleName := moduleName(key)
-		module.name = moduleName
-		module.isModule = true
-		module.flavor = moduleFlavorName(key)
-		newModules[mapKeyForName(moduleName)] = module
-	}
-	configSpec.Modules = newModules
+	return nil
+}
 
-	newAddons := map[string]Package{}
-	for key, addon := range temporaryConfig.AddOns {
-		addon.name = key
-		addon.isModule = false
-		newAddons[mapKeyForName(key)] = addon
+// MarshalYAML conform to Marshaler interface for writing the modules and addons maps
+// with the keys used in the configuration file
+func (configSpec ConfigSpec) MarshalYAML() (interface{}, error) {
+	return shadowConfigSpec{
+		Modules: packagesToConfig(configSpec.Modules),
+		AddOns:  packagesToConfig(configSpec.AddOns),
+		Source:  configSpec.Source,
+		Groups:  configSpec.Groups,
+	}, nil
+}
+
+type shadowGroup struct {
+
+	// The group name
+	Name string `yaml:"name,omitempty"`
+
+	// Labels contains arbitrary key/value pairs used for selecting clusters
+	// These labels are inherited by every cluster of the group
+	Labels map[string]string `yaml:"labels,omitempty"`
+
+	// Dictionary of Modules
+	// This field can be used to add a new module
+	// or patch/disable a default module for every cluster of the group
+	// Modules in the dictionary are referenced by "module-name/flavor-name"
+	// For example: ingress/traefik, cni/cilium, etc.
+	Modules map[string]Package `yaml:"modules,omitempty"`
+
+	// Dictionary of AddOns
+	// This field can be used to add a new add-on
+	// or patch/disable a default add-on for every cluster of the group
+	// AddOns in the dictionary are referenced by their name
+	AddOns map[string]Package `yaml:"addOns,omitempty"`
+
+	// Clusters contains the list of the clusters in the group
+	Clusters []Cluster `yaml:"clusters,omitempty"`
+}
+
+// UnmarshalYAML conform to Unmarshaler interface for customize the modules and addons
+// maps enriching the Package structs with additionals information
+func (group *Group) UnmarshalYAML(value *yaml.Node) error {
+	var temporaryGroup shadowGroup
+
+	if err := value.Decode(&temporaryGroup); err != nil {
+		return err
 	}
-	configSpec.AddOns = newAddons
+
+	group.Name = temporaryGroup.Name
+	group.Labels = temporaryGroup.Labels
+	group.Clusters = temporaryGroup.Clusters
+	group.Modules = ModulesFromConfig(temporaryGroup.Modules)
+	group.AddOns = AddOnsFromConfig(temporaryGroup.AddOns)
 
 	return nil
 }
 
+// MarshalYAML conform to Marshaler interface for writing the modules and addons maps
+// with the keys used in the configuration file
+func (group Group) MarshalYAML() (interface{}, error) {
+	return shadowGroup{
+		Name:     group.Name,
+		Labels:   group.Labels,
+		Modules:  packagesToConfig(group.Modules),
+		AddOns:   packagesToConfig(group.AddOns),
+		Clusters: group.Clusters,
+	}, nil
+}
+
 type shadowCluster struct {
 
 	// The cluster name
@@ -103,6 +158,10 @@ type shadowCluster struct {
 	// Name of the context used by the cluster
 	Context string `yaml:"context,omitempty"`
 
+	// Labels contains arbitrary key/value pairs used for selecting clusters
+	// They are merged with the labels of the group, overriding them in case of conflicts
+	Labels map[string]string `yaml:"labels,omitempty"`
+
 	// Dictionary of Modules
 	// This field can be used to add a new module
 	// or patch/disable a default module
@@ -128,24 +187,68 @@ func (cluster *Cluster) UnmarshalYAML(value *yaml.Node) error {
 
 	cluster.Name = temporaryCluster.Name
 	cluster.Context = temporaryCluster.Context
+	cluster.Labels = temporaryCluster.Labels
+	cluster.Modules = ModulesFromConfig(temporaryCluster.Modules)
+	cluster.AddOns = AddOnsFromConfig(temporaryCluster.AddOns)
 
+	return nil
+}
+
+// MarshalYAML conform to Marshaler interface for writing the modules and addons maps
+// with the keys used in the configuration file
+func (cluster Cluster) MarshalYAML() (interface{}, error) {
+	return shadowCluster{
+		Name:    cluster.Name,
+		Context: cluster.Context,
+		Labels:  cluster.Labels,
+		Modules: packagesToConfig(cluster.Modules),
+		AddOns:  packagesToConfig(cluster.AddOns),
+	}, nil
+}
+
+// ModulesFromConfig return a new map of modules enriched with the information contained in the keys
+// used in the configuration file, and keyed by the module name
+func ModulesFromConfig(modules map[string]Package) map[string]Package {
 	newModules := map[string]Package{}
-	for key, module := range temporaryCluster.Modules {
+	for key, module := range modules {
 		moduleName := moduleName(key)
 		module.name = moduleName
 		module.isModule = true
 		module.flavor = moduleFlavorName(key)
 		newModules[mapKeyForName(moduleName)] = module
 	}
-	cluster.Modules = newModules
 
+	return newModules
+}
+
+// AddOnsFromConfig return a new map of add-ons enriched with the information contained in the keys
+// used in the configuration file, and keyed by the add-on name
+func AddOnsFromConfig(addOns map[string]Package) map[string]Package {
 	newAddons := map[string]Package{}
-	for key, addon := range temporaryCluster.AddOns {
+	for key, addon := range addOns {
 		addon.name = key
 		addon.isModule = false
 		newAddons[mapKeyForName(key)] = addon
 	}
-	cluster.AddOns = newAddons
 
-	return nil
+	return newAddons
+}
+
+// packagesToConfig return a new map of packages keyed by the keys used in the configuration file,
+// that for the modules include the flavor name
+func packagesToConfig(packages map[string]Package) map[string]Package {
+	if packages == nil {
+		return nil
+	}
+
+	configPackages := make(map[string]Package, len(packages))
+	for _, pkg := range packages {
+		key := pkg.name
+		if pkg.isModule {
+			key += "/" + pkg.flavor
+		}
+		configPackages[key] = pkg
+	}
+
+	return configPackages
 }
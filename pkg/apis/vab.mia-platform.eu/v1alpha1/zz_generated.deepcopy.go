@@ -23,6 +23,13 @@ package v1alpha1
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Cluster) DeepCopyInto(out *Cluster) {
 	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	if in.Modules != nil {
 		in, out := &in.Modules, &out.Modules
 		*out = make(map[string]Package, len(*in))
@@ -85,6 +92,7 @@ func (in *ConfigSpec) DeepCopyInto(out *ConfigSpec) {
 			(*out)[key] = val
 		}
 	}
+	out.Source = in.Source
 	if in.Groups != nil {
 		in, out := &in.Groups, &out.Groups
 		*out = make([]Group, len(*in))
@@ -108,6 +116,27 @@ func (in *ConfigSpec) DeepCopy() *ConfigSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Group) DeepCopyInto(out *Group) {
 	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Modules != nil {
+		in, out := &in.Modules, &out.Modules
+		*out = make(map[string]Package, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.AddOns != nil {
+		in, out := &in.AddOns, &out.AddOns
+		*out = make(map[string]Package, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	if in.Clusters != nil {
 		in, out := &in.Clusters, &out.Clusters
 		*out = make([]Cluster, len(*in))
@@ -128,9 +157,42 @@ func (in *Group) DeepCopy() *Group {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LockedPackage) DeepCopyInto(out *LockedPackage) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LockedPackage.
+func (in *LockedPackage) DeepCopy() *LockedPackage {
+	if in == nil {
+		return nil
+	}
+	out := new(LockedPackage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LockedRange) DeepCopyInto(out *LockedRange) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LockedRange.
+func (in *LockedRange) DeepCopy() *LockedRange {
+	if in == nil {
+		return nil
+	}
+	out := new(LockedRange)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Package) DeepCopyInto(out *Package) {
 	*out = *in
+	out.Source = in.Source
 	return
 }
 
@@ -144,6 +206,49 @@ func (in *Package) DeepCopy() *Package {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackagesLock) DeepCopyInto(out *PackagesLock) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	if in.Packages != nil {
+		in, out := &in.Packages, &out.Packages
+		*out = make([]LockedPackage, len(*in))
+		copy(*out, *in)
+	}
+	if in.Ranges != nil {
+		in, out := &in.Ranges, &out.Ranges
+		*out = make([]LockedRange, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackagesLock.
+func (in *PackagesLock) DeepCopy() *PackagesLock {
+	if in == nil {
+		return nil
+	}
+	out := new(PackagesLock)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Source) DeepCopyInto(out *Source) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Source.
+func (in *Source) DeepCopy() *Source {
+	if in == nil {
+		return nil
+	}
+	out := new(Source)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TypeMeta) DeepCopyInto(out *TypeMeta) {
 	*out = *in
@@ -0,0 +1,170 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha2
+
+import "github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
+
+// ConvertToV1alpha1 return the v1alpha1 configuration equivalent to config, the kind is kept as is for allowing
+// its validation. The include lists are dropped, so the included groups and clusters must be already merged
+func ConvertToV1alpha1(config *ClustersConfiguration) *v1alpha1.ClustersConfiguration {
+	converted := &v1alpha1.ClustersConfiguration{
+		TypeMeta: v1alpha1.TypeMeta{
+			Kind:       config.Kind,
+			APIVersion: v1alpha1.Version,
+		},
+		Name: config.Name,
+		Spec: v1alpha1.ConfigSpec{
+			Modules: modulesToV1alpha1(config.Spec.Modules),
+			AddOns:  addOnsToV1alpha1(config.Spec.AddOns),
+			Source:  v1alpha1.Source(config.Spec.Source),
+		},
+	}
+
+	if config.Spec.Groups != nil {
+		converted.Spec.Groups = make([]v1alpha1.Group, 0, len(config.Spec.Groups))
+	}
+	for _, group := range config.Spec.Groups {
+		convertedGroup := v1alpha1.Group{
+			Name:    group.Name,
+			Labels:  group.Labels,
+			Modules: modulesToV1alpha1(group.Modules),
+			AddOns:  addOnsToV1alpha1(group.AddOns),
+		}
+		if group.Clusters != nil {
+			convertedGroup.Clusters = make([]v1alpha1.Cluster, 0, len(group.Clusters))
+		}
+		for _, cluster := range group.Clusters {
+			convertedGroup.Clusters = append(convertedGroup.Clusters, v1alpha1.Cluster{
+				Name:    cluster.Name,
+				Context: cluster.Context,
+				Labels:  cluster.Labels,
+				Modules: modulesToV1alpha1(cluster.Modules),
+				AddOns:  addOnsToV1alpha1(cluster.AddOns),
+			})
+		}
+		converted.Spec.Groups = append(converted.Spec.Groups, convertedGroup)
+	}
+
+	return converted
+}
+
+// ConvertFromV1alpha1 return the v1alpha2 configuration equivalent to config
+func ConvertFromV1alpha1(config *v1alpha1.ClustersConfiguration) *ClustersConfiguration {
+	converted := &ClustersConfiguration{
+		TypeMeta: TypeMeta{
+			Kind:       config.Kind,
+			APIVersion: Version,
+		},
+		Name: config.Name,
+		Spec: ConfigSpec{
+			Modules: modulesFromV1alpha1(config.Spec.Modules),
+			AddOns:  addOnsFromV1alpha1(config.Spec.AddOns),
+			Source:  Source(config.Spec.Source),
+		},
+	}
+
+	if config.Spec.Groups != nil {
+		converted.Spec.Groups = make([]Group, 0, len(config.Spec.Groups))
+	}
+	for _, group := range config.Spec.Groups {
+		convertedGroup := Group{
+			Name:    group.Name,
+			Labels:  group.Labels,
+			Modules: modulesFromV1alpha1(group.Modules),
+			AddOns:  addOnsFromV1alpha1(group.AddOns),
+		}
+		if group.Clusters != nil {
+			convertedGroup.Clusters = make([]Cluster, 0, len(group.Clusters))
+		}
+		for _, cluster := range group.Clusters {
+			convertedGroup.Clusters = append(convertedGroup.Clusters, Cluster{
+				Name:    cluster.Name,
+				Context: cluster.Context,
+				Labels:  cluster.Labels,
+				Modules: modulesFromV1alpha1(cluster.Modules),
+				AddOns:  addOnsFromV1alpha1(cluster.AddOns),
+			})
+		}
+		converted.Spec.Groups = append(converted.Spec.Groups, convertedGroup)
+	}
+
+	return converted
+}
+
+// modulesToV1alpha1 return the v1alpha1 packages for modules, whose keys include the flavor
+func modulesToV1alpha1(modules map[string]Module) map[string]v1alpha1.Package {
+	packages := make(map[string]v1alpha1.Package, len(modules))
+	for name, module := range modules {
+		packages[name+"/"+module.Flavor] = v1alpha1.Package{
+			Version: module.Version,
+			Disable: module.Disable,
+			Source:  v1alpha1.Source(module.Source),
+		}
+	}
+
+	return v1alpha1.ModulesFromConfig(packages)
+}
+
+// addOnsToV1alpha1 return the v1alpha1 packages for addOns
+func addOnsToV1alpha1(addOns map[string]AddOn) map[string]v1alpha1.Package {
+	packages := make(map[string]v1alpha1.Package, len(addOns))
+	for name, addOn := range addOns {
+		packages[name] = v1alpha1.Package{
+			Version: addOn.Version,
+			Disable: addOn.Disable,
+			Source:  v1alpha1.Source(addOn.Source),
+		}
+	}
+
+	return v1alpha1.AddOnsFromConfig(packages)
+}
+
+// modulesFromV1alpha1 return the modules for the v1alpha1 packages, keyed by their name
+func modulesFromV1alpha1(packages map[string]v1alpha1.Package) map[string]Module {
+	if packages == nil {
+		return nil
+	}
+
+	modules := make(map[string]Module, len(packages))
+	for _, pkg := range packages {
+		modules[pkg.GetName()] = Module{
+			Flavor:  pkg.GetFlavorName(),
+			Version: pkg.Version,
+			Disable: pkg.Disable,
+			Source:  Source(pkg.Source),
+		}
+	}
+
+	return modules
+}
+
+// addOnsFromV1alpha1 return the add-ons for the v1alpha1 packages, keyed by their name
+func addOnsFromV1alpha1(packages map[string]v1alpha1.Package) map[string]AddOn {
+	if packages == nil {
+		return nil
+	}
+
+	addOns := make(map[string]AddOn, len(packages))
+	for _, pkg := range packages {
+		addOns[pkg.GetName()] = AddOn{
+			Version: pkg.Version,
+			Disable: pkg.Disable,
+			Source:  Source(pkg.Source),
+		}
+	}
+
+	return addOns
+}
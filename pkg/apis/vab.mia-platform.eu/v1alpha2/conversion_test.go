@@ -0,0 +1,94 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
+)
+
+func TestConversion(t *testing.T) {
+	t.Parallel()
+
+	config := &ClustersConfiguration{
+		TypeMeta: TypeMeta{Kind: Kind, APIVersion: Version},
+		Name:     "test",
+		Spec: ConfigSpec{
+			Modules: map[string]Module{
+				"ingress/traefik": {Flavor: "base", Version: "1.20.1"},
+			},
+			AddOns: map[string]AddOn{
+				"monitoring/traefik": {Version: "1.20.1", Source: Source{URL: "file:///srv/git/addons.git"}},
+			},
+			Source: Source{Path: "kubernetes"},
+			Groups: []Group{
+				{
+					Name:    "group-1",
+					Labels:  map[string]string{"env": "prod"},
+					Modules: map[string]Module{"ingress/traefik": {Disable: true}},
+					AddOns:  map[string]AddOn{},
+					Clusters: []Cluster{
+						{
+							Name:    "cluster-1",
+							Context: "context-1",
+							Modules: map[string]Module{"cni/cilium": {Flavor: "eks", Version: "1.20.2"}},
+							AddOns:  map[string]AddOn{"monitoring/traefik": {Disable: true}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	converted := ConvertToV1alpha1(config)
+	assert.Equal(t, v1alpha1.TypeMeta{Kind: v1alpha1.Kind, APIVersion: v1alpha1.Version}, converted.TypeMeta)
+	assert.Equal(t, v1alpha1.Source{Path: "kubernetes"}, converted.Spec.Source)
+
+	packages := make(map[string]v1alpha1.Package)
+	for _, pkg := range converted.Spec.Groups[0].Clusters[0].Modules {
+		packages[pkg.GetName()] = pkg
+	}
+	for _, pkg := range converted.Spec.Modules {
+		packages[pkg.GetName()] = pkg
+	}
+	require.Contains(t, packages, "cni/cilium")
+	assert.True(t, packages["cni/cilium"].IsModule())
+	assert.Equal(t, "eks", packages["cni/cilium"].GetFlavorName())
+	assert.Equal(t, "1.20.2", packages["cni/cilium"].Version)
+	require.Contains(t, packages, "ingress/traefik")
+	assert.Equal(t, "base", packages["ingress/traefik"].GetFlavorName())
+
+	for _, pkg := range converted.Spec.AddOns {
+		assert.False(t, pkg.IsModule())
+		assert.Equal(t, "monitoring/traefik", pkg.GetName())
+		assert.Equal(t, v1alpha1.Source{URL: "file:///srv/git/addons.git"}, pkg.Source)
+	}
+
+	assert.Equal(t, config, ConvertFromV1alpha1(converted))
+}
+
+func TestConversionOfEmptyConfig(t *testing.T) {
+	t.Parallel()
+
+	config := EmptyConfig("empty")
+	converted := ConvertToV1alpha1(config)
+	assert.Equal(t, v1alpha1.EmptyConfig("empty"), converted)
+	assert.Equal(t, config, ConvertFromV1alpha1(converted))
+}
@@ -0,0 +1,22 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package v1alpha2 implements the v1alpha2 apiVersion of vab's cluster
+// configuration, where the flavor of a module is a property of the module
+// instead of being part of its key. The configuration is converted to
+// v1alpha1 for being used by the commands
+//
+// +k8s:deepcopy-gen=package
+package v1alpha2
@@ -0,0 +1,177 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha2
+
+// TypeMeta partially copies apimachinery/pkg/apis/meta/v1.TypeMeta
+type TypeMeta struct {
+	Kind       string `json:"kind,omitempty" yaml:"kind,omitempty"`
+	APIVersion string `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
+}
+
+// ClustersConfiguration contains the schema for vab's configuration
+type ClustersConfiguration struct {
+	TypeMeta `json:",inline" yaml:",inline"`
+
+	// The configuration name
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// ConfigSpec contains the configuration of the clusters
+	// It includes the modules and add-ons installed by default
+	// as well as the list of cluster groups
+	Spec ConfigSpec `json:"spec" yaml:"spec"`
+}
+
+// ConfigSpec contains the configuration of the clusters
+type ConfigSpec struct {
+
+	// Dictionary of Modules
+	// These modules will be installed on every cluster
+	// unless otherwise specified
+	// Modules in the dictionary are referenced by their name, without the flavor
+	// For example: ingress/traefik, cni/cilium, etc.
+	Modules map[string]Module `json:"modules" yaml:"modules"`
+
+	// Dictionary of AddOns
+	// These add-ons will be installed on every cluster
+	// unless otherwise specified
+	// AddOns in the dictionary are referenced by their name
+	AddOns map[string]AddOn `json:"addOns" yaml:"addOns"`
+
+	// Source of the packages that don't specify their own
+	// If not set the packages are downloaded from the Mia-Platform distribution repository
+	Source Source `json:"source,omitempty" yaml:"source,omitempty"`
+
+	// Groups contains the list of cluster groups
+	Groups []Group `json:"groups" yaml:"groups"`
+
+	// Include contains the paths of other files defining a cluster group each,
+	// relative to the configuration file and optionally with glob patterns like groups/*.yaml
+	// The included groups are appended to the Groups list in the order of the paths,
+	// and the files matched by a pattern in lexical order
+	Include []string `json:"include,omitempty" yaml:"include,omitempty"`
+}
+
+// Group contains the configuration of a cluster group
+type Group struct {
+
+	// The group name
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// Labels contains arbitrary key/value pairs used for selecting clusters
+	// These labels are inherited by every cluster of the group
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+
+	// Dictionary of Modules
+	// This field can be used to add a new module
+	// or patch/disable a default module for every cluster of the group
+	// Modules in the dictionary are referenced by their name, without the flavor
+	Modules map[string]Module `json:"modules,omitempty" yaml:"modules,omitempty"`
+
+	// Dictionary of AddOns
+	// This field can be used to add a new add-on
+	// or patch/disable a default add-on for every cluster of the group
+	// AddOns in the dictionary are referenced by their name
+	AddOns map[string]AddOn `json:"addOns,omitempty" yaml:"addOns,omitempty"`
+
+	// Clusters contains the list of the clusters in the group
+	// This field is required to reference the clusters correctly
+	// in the directory structure
+	Clusters []Cluster `json:"clusters,omitempty" yaml:"clusters,omitempty"`
+
+	// Include contains the paths of other files defining a cluster each,
+	// relative to the file defining the group and optionally with glob patterns
+	// The included clusters are appended to the Clusters list like the groups of the spec
+	Include []string `json:"include,omitempty" yaml:"include,omitempty"`
+}
+
+// Cluster contains the configuration of a cluster
+// and customizations of its modules/add-ons
+type Cluster struct {
+
+	// The cluster name
+	// It is required to reference the cluster directory
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// Name of the context used by the cluster
+	Context string `json:"context,omitempty" yaml:"context,omitempty"`
+
+	// Labels contains arbitrary key/value pairs used for selecting clusters
+	// They are merged with the labels of the group, overriding them in case of conflicts
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+
+	// Dictionary of Modules
+	// This field can be used to add a new module
+	// or patch/disable a default module
+	// Modules in the dictionary are referenced by their name, without the flavor
+	Modules map[string]Module `json:"modules,omitempty" yaml:"modules,omitempty"`
+
+	// Dictionary of AddOns
+	// This field can be used to add a new add-on
+	// or patch/disable a default add-on
+	// AddOns in the dictionary are referenced by their name
+	AddOns map[string]AddOn `json:"addOns,omitempty" yaml:"addOns,omitempty"`
+}
+
+// Module contains the flavor, version and status of a module
+type Module struct {
+
+	// Flavor of the module to be installed, it can be omitted for disabling the module
+	Flavor string `json:"flavor,omitempty" yaml:"flavor,omitempty"`
+
+	// Version of the module to be installed
+	Version string `json:"version,omitempty" yaml:"version,omitempty"`
+
+	// Flag that disables the module if set to true
+	Disable bool `json:"disable,omitempty" yaml:"disable,omitempty"`
+
+	// Source of the module, if not set the source of the configuration will be used
+	Source Source `json:"source,omitempty" yaml:"source,omitempty"`
+}
+
+// AddOn contains the version and status of an add-on
+type AddOn struct {
+
+	// Version of the add-on to be installed
+	Version string `json:"version,omitempty" yaml:"version,omitempty"`
+
+	// Flag that disables the add-on if set to true
+	Disable bool `json:"disable,omitempty" yaml:"disable,omitempty"`
+
+	// Source of the add-on, if not set the source of the configuration will be used
+	Source Source `json:"source,omitempty" yaml:"source,omitempty"`
+}
+
+// Source contains the information for downloading packages from a git repository
+type Source struct {
+
+	// URL of the git repository, it can be any url supported by git, including local paths
+	// and file:// urls
+	URL string `json:"url,omitempty" yaml:"url,omitempty"`
+
+	// Path of the folder inside the repository containing the modules and addons folders
+	// If empty the repository root will be used
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+
+	// TagScheme is the template used for building the tag of a package version
+	// It can contain the {type}, {name} and {version} placeholders, and if empty
+	// the {type}-{name}-{version} scheme will be used
+	TagScheme string `json:"tagScheme,omitempty" yaml:"tagScheme,omitempty"`
+
+	// Credentials is the name of the credentials used for connecting to the repository
+	// Their values are read from the VAB_CREDENTIALS_<NAME>_* environment variables and
+	// are never written in the configuration file
+	Credentials string `json:"credentials,omitempty" yaml:"credentials,omitempty"`
+}
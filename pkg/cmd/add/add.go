@@ -0,0 +1,250 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package add
+
+import (
+	"context"
+	"errors"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/mia-platform/vab/internal/git"
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
+	"github.com/mia-platform/vab/pkg/cmd/util"
+)
+
+const (
+	shortCmd = "Add packages, groups and clusters to the configuration file"
+	longCmd  = `Add modules, add-ons, groups and clusters to the configuration file, and update
+	the clusters folders of the project for matching the new configuration.
+
+	The project is the folder containing the configuration file, and the file is edited
+	in place keeping its comments and ordering.`
+
+	shortPackageCmd = "Add the %[1]s NAME to the configuration file"
+	longPackageCmd  = `Add the %[1]s with NAME to the configuration file, or update it if already present.
+
+	By default the %[1]s is added for all the clusters; with the --group and --cluster flags
+	it is added only to a group or a cluster, overriding the default one if present. The
+	--disable flag disables the default %[1]s for the group or the cluster.
+
+	A version range is kept in the configuration file, while the clusters folders use the
+	version recorded for it in the lock file or the newest matching tag of its repository.`
+
+	shortGroupCmd = "Add a group to the configuration file"
+	longGroupCmd  = `Add an empty group with NAME to the configuration file.`
+
+	shortClusterCmd = "Add a cluster to a group of the configuration file"
+	longClusterCmd  = `Add a cluster with NAME to GROUP in the configuration file, using the kubernetes
+	context set with the --context flag.`
+
+	versionFlagName = "version"
+	versionUsage    = "the version of the package, it can be a single version or a range"
+	disableFlagName = "disable"
+	disableUsage    = "disable the package in the group or cluster instead of setting its version"
+	groupFlagName   = "group"
+	groupUsage      = "the group where the package is added"
+	clusterFlagName = "cluster"
+	clusterUsage    = "the cluster of the group where the package is added"
+	contextFlagName = "context"
+	contextUsage    = "the name of the kubernetes context used for the cluster"
+)
+
+// Flags contains all the flags for the `add` subcommands. They will be converted to Options
+// that contains all runtime options for the command.
+type Flags struct {
+	version string
+	disable bool
+	group   string
+	cluster string
+	context string
+}
+
+// AddPackageFlags set the connection between Flags property to command line flags for the package subcommands
+func (f *Flags) AddPackageFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&f.version, versionFlagName, "", heredoc.Doc(versionUsage))
+	flags.BoolVar(&f.disable, disableFlagName, false, heredoc.Doc(disableUsage))
+	flags.StringVar(&f.group, groupFlagName, "", heredoc.Doc(groupUsage))
+	flags.StringVar(&f.cluster, clusterFlagName, "", heredoc.Doc(clusterUsage))
+}
+
+// AddClusterFlags set the connection between Flags property to command line flags for the cluster subcommand
+func (f *Flags) AddClusterFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&f.context, contextFlagName, "", heredoc.Doc(contextUsage))
+}
+
+// Options have the data required to perform the add operation
+type Options struct {
+	configPath  string
+	contextPath string
+	edit        func(*util.ConfigEditor) error
+	filesGetter *git.FilesGetter
+}
+
+// NewCommand return the command for adding packages, groups and clusters to the configuration file
+func NewCommand(cf *util.ConfigFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "add",
+		Short: heredoc.Doc(shortCmd),
+		Long:  heredoc.Doc(longCmd),
+
+		Args: cobra.NoArgs,
+	}
+
+	cmd.AddCommand(
+		newPackageCommand(cf, "module"),
+		newPackageCommand(cf, "addon"),
+		newGroupCommand(cf),
+		newClusterCommand(cf),
+	)
+	return cmd
+}
+
+func newPackageCommand(cf *util.ConfigFlags, pkgType string) *cobra.Command {
+	flags := &Flags{}
+	cmd := &cobra.Command{
+		Use:   pkgType + " NAME",
+		Short: heredoc.Docf(shortPackageCmd, pkgType),
+		Long:  heredoc.Docf(longPackageCmd, pkgType),
+
+		Args: cobra.ExactArgs(1),
+
+		Run: func(cmd *cobra.Command, args []string) {
+			options, err := flags.ToPackageOptions(cf, pkgType, args[0])
+			cobra.CheckErr(err)
+			cobra.CheckErr(options.Run(cmd.Context()))
+		},
+	}
+
+	flags.AddPackageFlags(cmd.Flags())
+	return cmd
+}
+
+func newGroupCommand(cf *util.ConfigFlags) *cobra.Command {
+	flags := &Flags{}
+	cmd := &cobra.Command{
+		Use:   "group NAME",
+		Short: heredoc.Doc(shortGroupCmd),
+		Long:  heredoc.Doc(longGroupCmd),
+
+		Args: cobra.ExactArgs(1),
+
+		Run: func(cmd *cobra.Command, args []string) {
+			options, err := flags.ToGroupOptions(cf, args[0])
+			cobra.CheckErr(err)
+			cobra.CheckErr(options.Run(cmd.Context()))
+		},
+	}
+
+	return cmd
+}
+
+func newClusterCommand(cf *util.ConfigFlags) *cobra.Command {
+	flags := &Flags{}
+	cmd := &cobra.Command{
+		Use:   "cluster GROUP NAME",
+		Short: heredoc.Doc(shortClusterCmd),
+		Long:  heredoc.Doc(longClusterCmd),
+
+		Args: cobra.ExactArgs(2),
+
+		Run: func(cmd *cobra.Command, args []string) {
+			options, err := flags.ToClusterOptions(cf, args[0], args[1])
+			cobra.CheckErr(err)
+			cobra.CheckErr(options.Run(cmd.Context()))
+		},
+	}
+
+	flags.AddClusterFlags(cmd.Flags())
+	return cmd
+}
+
+// ToPackageOptions transform the command flags in runtime arguments for adding the package of pkgType with key
+func (f *Flags) ToPackageOptions(cf *util.ConfigFlags, pkgType, key string) (*Options, error) {
+	if err := util.ValidatePackageKey(pkgType, key); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case len(f.cluster) > 0 && len(f.group) == 0:
+		return nil, errors.New("the --cluster flag needs the --group flag")
+	case f.disable && len(f.version) > 0:
+		return nil, errors.New("the --version and --disable flags cannot be used together")
+	case f.disable && len(f.group) == 0:
+		return nil, errors.New("the --disable flag needs the --group flag")
+	case !f.disable && len(f.version) == 0:
+		return nil, errors.New("the --version flag is required")
+	}
+
+	if util.IsVersionRange(f.version) {
+		if _, err := util.ParseVersionRange(f.version); err != nil {
+			return nil, err
+		}
+	}
+
+	scope := util.ConfigScope{Group: f.group, Cluster: f.cluster}
+	pkg := v1alpha1.Package{Version: f.version, Disable: f.disable}
+	return newOptions(cf, func(editor *util.ConfigEditor) error {
+		return editor.SetPackage(scope, pkgType, key, pkg)
+	})
+}
+
+// ToGroupOptions transform the command flags in runtime arguments for adding the group with name
+func (f *Flags) ToGroupOptions(cf *util.ConfigFlags, name string) (*Options, error) {
+	if err := util.ValidateGroupName(name); err != nil {
+		return nil, err
+	}
+
+	return newOptions(cf, func(editor *util.ConfigEditor) error {
+		return editor.AddGroup(name)
+	})
+}
+
+// ToClusterOptions transform the command flags in runtime arguments for adding the cluster with name to group
+func (f *Flags) ToClusterOptions(cf *util.ConfigFlags, group, name string) (*Options, error) {
+	if err := util.ValidateClusterName(name); err != nil {
+		return nil, err
+	}
+	if len(f.context) == 0 {
+		return nil, errors.New("the --context flag is required")
+	}
+
+	return newOptions(cf, func(editor *util.ConfigEditor) error {
+		return editor.AddCluster(group, name, f.context)
+	})
+}
+
+// newOptions return the Options for applying edit to the configuration file, the project is the folder containing it
+func newOptions(cf *util.ConfigFlags, edit func(*util.ConfigEditor) error) (*Options, error) {
+	configPath, contextPath, err := util.ProjectPaths(cf)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Options{
+		configPath:  configPath,
+		contextPath: contextPath,
+		edit:        edit,
+		filesGetter: git.NewFilesGetter(),
+	}, nil
+}
+
+// Run execute the add command
+func (o *Options) Run(ctx context.Context) error {
+	return util.EditProject(ctx, o.configPath, o.contextPath, o.edit, o.filesGetter.ListVersions)
+}
@@ -0,0 +1,219 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package add
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mia-platform/vab/internal/git"
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
+	"github.com/mia-platform/vab/pkg/cmd/util"
+)
+
+func TestCommand(t *testing.T) {
+	t.Parallel()
+
+	cmd := NewCommand(util.NewConfigFlags())
+	assert.NotNil(t, cmd)
+	assert.Len(t, cmd.Commands(), 4)
+}
+
+func TestToPackageOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		flags         *Flags
+		pkgType       string
+		key           string
+		expectedError string
+	}{
+		"valid module": {
+			flags:   &Flags{version: "1.2.3"},
+			pkgType: "module",
+			key:     "cni/cilium/base",
+		},
+		"valid range": {
+			flags:   &Flags{version: "~1.2.0", group: "group", cluster: "cluster"},
+			pkgType: "addon",
+			key:     "monitoring/traefik",
+		},
+		"module without flavor": {
+			flags:         &Flags{version: "1.2.3"},
+			pkgType:       "module",
+			key:           "cni/cilium",
+			expectedError: `invalid module "cni/cilium": it must be in the form category/name/flavor`,
+		},
+		"missing version": {
+			flags:         &Flags{},
+			pkgType:       "addon",
+			key:           "monitoring/traefik",
+			expectedError: "the --version flag is required",
+		},
+		"invalid range": {
+			flags:         &Flags{version: "^a"},
+			pkgType:       "addon",
+			key:           "monitoring/traefik",
+			expectedError: `invalid version range "^a"`,
+		},
+		"cluster without group": {
+			flags:         &Flags{version: "1.2.3", cluster: "cluster"},
+			pkgType:       "addon",
+			key:           "monitoring/traefik",
+			expectedError: "the --cluster flag needs the --group flag",
+		},
+		"disable with version": {
+			flags:         &Flags{version: "1.2.3", disable: true, group: "group"},
+			pkgType:       "addon",
+			key:           "monitoring/traefik",
+			expectedError: "the --version and --disable flags cannot be used together",
+		},
+		"disable without group": {
+			flags:         &Flags{disable: true},
+			pkgType:       "addon",
+			key:           "monitoring/traefik",
+			expectedError: "the --disable flag needs the --group flag",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			options, err := test.flags.ToPackageOptions(util.NewTestConfigFlags(t, filepath.Join("testdata", "config.yaml")), test.pkgType, test.key)
+			if len(test.expectedError) > 0 {
+				assert.ErrorContains(t, err, test.expectedError)
+				assert.Nil(t, options)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.NotNil(t, options)
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	configFlags := util.NewTestConfigFlags(t, filepath.Join("testdata", "config.yaml"))
+	contextPath := filepath.Dir(*configFlags.ConfigPath)
+
+	run := func(options *Options, err error) {
+		t.Helper()
+		require.NoError(t, err)
+		require.NoError(t, options.Run(t.Context()))
+	}
+
+	run((&Flags{}).ToGroupOptions(configFlags, "new-group"))
+	run((&Flags{context: "new-context"}).ToClusterOptions(configFlags, "new-group", "new-cluster"))
+	run((&Flags{version: "1.2.3"}).ToPackageOptions(configFlags, "module", "cni/cilium/base"))
+	run((&Flags{version: "1.21.0", group: "new-group", cluster: "new-cluster"}).ToPackageOptions(configFlags, "module", "ingress/traefik/base"))
+	run((&Flags{disable: true, group: "group"}).ToPackageOptions(configFlags, "module", "cni/cilium/base"))
+
+	data, err := os.ReadFile(*configFlags.ConfigPath)
+	require.NoError(t, err)
+	assert.Equal(t, `kind: ClustersConfiguration
+apiVersion: vab.mia-platform.eu/v1alpha1
+name: test
+spec:
+  modules:
+    # the default ingress
+    ingress/traefik/base:
+      version: 1.20.1
+    cni/cilium/base:
+      version: 1.2.3
+  addOns: {}
+  groups:
+  - name: group
+    clusters:
+    - name: cluster
+      context: context
+    modules:
+      cni/cilium/base:
+        disable: true
+  - name: new-group
+    clusters:
+    - name: new-cluster
+      context: new-context
+      modules:
+        ingress/traefik/base:
+          version: 1.21.0
+`, string(data))
+
+	bases, err := os.ReadFile(filepath.Join(contextPath, "clusters", "new-group", "new-cluster", "bases", "kustomization.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(bases), "ingress/traefik-1.21.0/base")
+	assert.Contains(t, string(bases), "cni/cilium-1.2.3/base")
+	assert.DirExists(t, filepath.Join(contextPath, "clusters", "group", "all-clusters"))
+
+	options, err := (&Flags{context: "context"}).ToClusterOptions(configFlags, "missing", "cluster")
+	require.NoError(t, err)
+	assert.ErrorContains(t, options.Run(t.Context()), `group "missing" not found`)
+
+	_, err = (&Flags{}).ToGroupOptions(configFlags, "all-groups")
+	assert.ErrorContains(t, err, `group name "all-groups" is reserved`)
+	_, err = (&Flags{}).ToClusterOptions(configFlags, "group", "cluster")
+	assert.ErrorContains(t, err, "the --context flag is required")
+}
+
+func TestRunWithVersionRanges(t *testing.T) {
+	t.Parallel()
+
+	configFlags := util.NewTestConfigFlags(t, filepath.Join("testdata", "config.yaml"))
+	contextPath := filepath.Dir(*configFlags.ConfigPath)
+	require.NoError(t, util.WriteLock(contextPath, nil, []v1alpha1.LockedRange{
+		{Type: "module", Name: "cni/cilium", Range: "~1.0.0", Repository: "https://github.com/mia-platform/distribution", Version: "v1.0.0"},
+	}))
+
+	run := func(version, pkgType, key string) {
+		t.Helper()
+		options, err := (&Flags{version: version}).ToPackageOptions(configFlags, pkgType, key)
+		require.NoError(t, err)
+		fg, _ := git.NewTestFilesGetter(t)
+		options.filesGetter = fg
+		require.NoError(t, options.Run(t.Context()))
+	}
+
+	run("~1.0.0", "module", "cni/cilium/base")
+	run("^1.0.0", "addon", "monitoring/prometheus")
+
+	data, err := os.ReadFile(*configFlags.ConfigPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "version: ~1.0.0")
+	assert.Contains(t, string(data), "version: ^1.0.0")
+
+	// the locked version is used even if the remote has a newer one matching the range
+	bases, err := os.ReadFile(filepath.Join(contextPath, "clusters", "all-groups", "bases", "kustomization.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(bases), "cni/cilium-v1.0.0/base")
+	assert.Contains(t, string(bases), "monitoring/prometheus-v1.1.0")
+	assert.NotContains(t, string(bases), "~1.0.0")
+
+	// the configuration file is not changed if a range cannot be resolved
+	options, err := (&Flags{version: "^3.0.0"}).ToPackageOptions(configFlags, "addon", "monitoring/grafana")
+	require.NoError(t, err)
+	fg, _ := git.NewTestFilesGetter(t)
+	options.filesGetter = fg
+	assert.ErrorContains(t, options.Run(t.Context()), `resolving version of addon monitoring/grafana: no version matches "^3.0.0"`)
+	data, err = os.ReadFile(*configFlags.ConfigPath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "monitoring/grafana")
+}
@@ -20,7 +20,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
+	"io"
 	"path/filepath"
 	"time"
 
@@ -34,25 +34,49 @@ import (
 	jplutil "github.com/mia-platform/jpl/pkg/util"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+	"golang.org/x/sync/errgroup"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/rest"
 
-	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
 	"github.com/mia-platform/vab/pkg/cmd/util"
 )
 
 const (
 	shortCmd = "Build and apply the local configuration"
 	longCmd  = `Builds and applies the local configuration to the specified cluster or group,
-	or to all of them`
-	cmdUsage = "apply GROUP [CLUSTER] CONTEXT"
+	or to all of them.
+
+	GROUP and CLUSTER accept glob patterns (e.g. "prod-*") for targeting multiple
+	groups or clusters at once, while the --all flag will target every cluster of
+	every group found in the configuration file.
+
+	The --selector flag filters the targeted clusters using the labels set on them
+	and on their groups (e.g. "env=prod,region!=eu").`
+	cmdUsage = "apply [--all | GROUP [CLUSTER]] CONTEXT"
+
+	allDefaultValue = false
+	allFlagName     = "all"
+	allUsage        = "if true apply to every cluster of every group in the configuration"
+
+	selectorFlagName      = "selector"
+	selectorFlagShortName = "l"
+	selectorUsage         = "label selector to filter the targeted clusters (e.g. env=prod,region!=eu)"
 
 	dryRunDefaultValue = false
 	dryRunFlagName     = "dry-run"
 	dryRunUsage        = "if true does not apply the configurations"
 
+	failFastDefaultValue = false
+	failFastFlagName     = "fail-fast"
+	failFastUsage        = "if true stop applying to the remaining clusters after the first failure"
+
+	concurrencyDefaultValue = 1
+	concurrencyFlagName     = "concurrency"
+	concurrencyUsage        = "the number of clusters to apply to in parallel"
+
 	timeoutDefaultValue = "0s"
 	timeoutFlagName     = "timeout"
 	timeoutFlagUsage    = `the length of time to wait before giving up.
@@ -62,20 +86,31 @@ const (
 
 	applyErrorFormat = "applying resources for %q: %w"
 
-	minArgs = 2
+	minArgs = 1
 	maxArgs = 3
 )
 
+// errStoppedAfterFailure is the cause of the cancellation of the clusters still applying when fail fast is set
+var errStoppedAfterFailure = errors.New("stopped after the first failure")
+
 // Flags contains all the flags for the `apply` command. They will be converted to Options
 // that contains all runtime options for the command.
 type Flags struct {
-	dryRun  bool
-	timeout string
+	all         bool
+	selector    string
+	dryRun      bool
+	failFast    bool
+	concurrency int
+	timeout     string
 }
 
 // AddFlags set the connection between Flags property to command line flags
 func (f *Flags) AddFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&f.all, allFlagName, allDefaultValue, heredoc.Doc(allUsage))
+	flags.StringVarP(&f.selector, selectorFlagName, selectorFlagShortName, "", heredoc.Doc(selectorUsage))
 	flags.BoolVar(&f.dryRun, dryRunFlagName, dryRunDefaultValue, heredoc.Doc(dryRunUsage))
+	flags.BoolVar(&f.failFast, failFastFlagName, failFastDefaultValue, heredoc.Doc(failFastUsage))
+	flags.IntVar(&f.concurrency, concurrencyFlagName, concurrencyDefaultValue, heredoc.Doc(concurrencyUsage))
 	flags.StringVar(&f.timeout, timeoutFlagName, timeoutDefaultValue, heredoc.Doc(timeoutFlagUsage))
 }
 
@@ -91,16 +126,26 @@ func defaultFactoryAndConfigfunc(context string) (jplutil.ClientFactory, *generi
 // Options have the data required to perform the apply operation
 type Options struct {
 	dryRun               bool
+	failFast             bool
+	concurrency          int
 	timeout              time.Duration
 	fieldManager         string
 	group                string
 	cluster              string
+	selector             labels.Selector
 	contextPath          string
 	configPath           string
 	factoryAndConfigFunc factoryAndConfigFunc
+	writer               io.Writer
 	logger               logr.Logger
 }
 
+// clusterEvent wrap an event received from the applier of the cluster identified by clusterID
+type clusterEvent struct {
+	clusterID string
+	event     event.Event
+}
+
 func NewCommand(cf *util.ConfigFlags) *cobra.Command {
 	flags := &Flags{}
 
@@ -111,7 +156,7 @@ func NewCommand(cf *util.ConfigFlags) *cobra.Command {
 
 		Args: cobra.RangeArgs(minArgs, maxArgs),
 		Run: func(cmd *cobra.Command, args []string) {
-			options, err := flags.ToOptions(cf, args)
+			options, err := flags.ToOptions(cf, args, cmd.ErrOrStderr())
 			cobra.CheckErr(err)
 			cobra.CheckErr(options.Run(cmd.Context()))
 		},
@@ -122,12 +167,10 @@ func NewCommand(cf *util.ConfigFlags) *cobra.Command {
 }
 
 // ToOptions transform the command flags in command runtime arguments
-func (f *Flags) ToOptions(cf *util.ConfigFlags, args []string) (*Options, error) {
-	group := args[0]
-	cluster := ""
-	contextPath := args[len(args)-1]
-	if len(args) >= maxArgs {
-		cluster = args[1]
+func (f *Flags) ToOptions(cf *util.ConfigFlags, args []string, writer io.Writer) (*Options, error) {
+	group, cluster, contextPath, err := util.ParseTargetArgs(args, f.all)
+	if err != nil {
+		return nil, err
 	}
 
 	cleanedContextPath, err := util.ValidateContextPath(contextPath)
@@ -140,6 +183,15 @@ func (f *Flags) ToOptions(cf *util.ConfigFlags, args []string) (*Options, error)
 		return nil, fmt.Errorf("failed to parse request timeout: %w", err)
 	}
 
+	selector, err := util.ParseSelector(f.selector)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.concurrency < 1 {
+		return nil, fmt.Errorf("invalid concurrency %d: must be greater than zero", f.concurrency)
+	}
+
 	configPath := ""
 	if cf.ConfigPath != nil && len(*cf.ConfigPath) > 0 {
 		configPath = filepath.Clean(*cf.ConfigPath)
@@ -147,13 +199,17 @@ func (f *Flags) ToOptions(cf *util.ConfigFlags, args []string) (*Options, error)
 
 	return &Options{
 		dryRun:               f.dryRun,
+		failFast:             f.failFast,
+		concurrency:          f.concurrency,
 		timeout:              timeout,
 		fieldManager:         "vab",
 		group:                group,
 		cluster:              cluster,
+		selector:             selector,
 		contextPath:          cleanedContextPath,
 		configPath:           configPath,
 		factoryAndConfigFunc: defaultFactoryAndConfigfunc,
+		writer:               writer,
 	}, nil
 }
 
@@ -161,68 +217,150 @@ func (f *Flags) ToOptions(cf *util.ConfigFlags, args []string) (*Options, error)
 func (o *Options) Run(ctx context.Context) error {
 	o.logger = logr.FromContextOrDiscard(ctx)
 
-	group, err := util.GroupFromConfig(o.group, o.configPath)
+	clusters, err := util.ClustersFromConfig(o.group, o.cluster, o.selector, o.configPath)
 	if err != nil {
 		return err
 	}
 
-	found := false
-	for _, cluster := range group.Clusters {
-		clusterName := cluster.Name
-		if o.cluster != "" && clusterName != o.cluster {
-			continue
-		}
+	return o.applyClusters(ctx, clusters)
+}
 
-		found = true
+// applyClusters apply the manifests to clusters running at most o.concurrency appliers at the same time,
+// the events of all the appliers are multiplexed on o.writer prefixed with the cluster they belong to.
+// When the apply of a cluster fails and o.failFast is set, the in-flight appliers are cancelled and the
+// remaining clusters are skipped: both are listed in the returned error apart from the failed ones
+func (o *Options) applyClusters(ctx context.Context, clusters []util.TargetCluster) error {
+	runCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	eventCh := make(chan clusterEvent)
+	printerDone := make(chan struct{})
+	go func() {
+		defer close(printerDone)
+		for clusterEvent := range eventCh {
+			fmt.Fprintf(o.writer, "%s: %s\n", clusterEvent.clusterID, clusterEvent.event.String())
+		}
+	}()
+
+	errs := make([]error, len(clusters))
+	cancelled := make([]bool, len(clusters))
+	skipped := make([]bool, len(clusters))
+	group := new(errgroup.Group)
+	group.SetLimit(max(o.concurrency, 1))
+	for idx, cluster := range clusters {
+		group.Go(func() error {
+			clusterID := cluster.ID()
+			if runCtx.Err() != nil {
+				o.logger.V(2).Info("skipping cluster", "cluster", clusterID, "reason", context.Cause(runCtx))
+				skipped[idx] = true
+				return nil
+			}
 
-		applyCtx, cancel := context.WithCancel(ctx)
-		defer cancel()
+			err := o.applyCluster(runCtx, cluster, eventCh)
+			switch {
+			case err == nil:
+			case errors.Is(context.Cause(runCtx), errStoppedAfterFailure):
+				// the cluster has been interrupted by the failure of another one, its error is caused by the
+				// cancellation and not counted as a failure
+				o.logger.V(2).Info("cluster cancelled", "cluster", clusterID, "error", err)
+				cancelled[idx] = true
+			default:
+				errs[idx] = err
+				if o.failFast {
+					o.logger.V(2).Info("stopping after first failure", "cluster", clusterID)
+					cancel(errStoppedAfterFailure)
+				}
+			}
+			return nil
+		})
+	}
 
-		clusterID := util.ClusterID(o.group, clusterName)
-		clusterLogger := o.logger.WithName(clusterID)
-		clusterLogger.V(2).Info("applying files")
+	_ = group.Wait()
+	close(eventCh)
+	<-printerDone
+
+	failedClusters := make([]string, 0)
+	cancelledClusters := make([]string, 0)
+	skippedClusters := make([]string, 0)
+	for idx, cluster := range clusters {
+		switch {
+		case errs[idx] != nil:
+			failedClusters = append(failedClusters, cluster.ID())
+		case cancelled[idx]:
+			cancelledClusters = append(cancelledClusters, cluster.ID())
+		case skipped[idx]:
+			skippedClusters = append(skippedClusters, cluster.ID())
+		}
+	}
 
-		eventCh, err := o.apply(applyCtx, cluster)
-		if err != nil {
-			return err
+	if len(failedClusters) > 0 {
+		summary := fmt.Sprintf("apply failed for %d of %d clusters %q", len(failedClusters), len(clusters), failedClusters)
+		if len(cancelledClusters) > 0 {
+			summary += fmt.Sprintf(", cancelled for %q", cancelledClusters)
+		}
+		if len(skippedClusters) > 0 {
+			summary += fmt.Sprintf(", skipped for %q", skippedClusters)
 		}
+		return fmt.Errorf("%s:\n%w", summary, errors.Join(errs...))
+	}
+
+	return ctx.Err()
+}
+
+// applyCluster apply the manifests for cluster and forward its events to eventCh until the applier has finished,
+// it will return an error if the applier cannot start or if any of the events is an error
+func (o *Options) applyCluster(ctx context.Context, cluster util.TargetCluster, eventCh chan<- clusterEvent) error {
+	applyCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	clusterID := cluster.ID()
+	clusterLogger := o.logger.WithName(clusterID)
+	clusterLogger.V(2).Info("applying files")
 
-		for {
-			select {
-			case event, open := <-eventCh:
-				if !open {
-					clusterLogger.V(2).Info("finish applying files")
-					return nil
+	applierCh, err := o.apply(applyCtx, cluster)
+	if err != nil {
+		return err
+	}
+
+	errorEvents := 0
+	for {
+		select {
+		case event, open := <-applierCh:
+			if !open {
+				clusterLogger.V(2).Info("finish applying files", "errors", errorEvents)
+				if errorEvents > 0 {
+					return fmt.Errorf(applyErrorFormat, clusterID, fmt.Errorf("%d errors during apply", errorEvents))
 				}
+				return nil
+			}
 
-				fmt.Fprintf(os.Stderr, "%s: %s\n", clusterID, event.String())
-			case <-applyCtx.Done():
-				return applyCtx.Err()
+			if event.IsErrorEvent() {
+				errorEvents++
 			}
+			eventCh <- clusterEvent{clusterID: clusterID, event: event}
+		case <-applyCtx.Done():
+			return fmt.Errorf(applyErrorFormat, clusterID, applyCtx.Err())
 		}
 	}
-
-	switch {
-	case !found && len(o.cluster) == 0:
-		return fmt.Errorf("group %q doesn't have any cluster", o.group)
-	case !found && len(o.cluster) != 0:
-		return fmt.Errorf("group %q doesn't have cluster %q", o.group, o.cluster)
-	}
-	return nil
 }
 
-func (o *Options) apply(ctx context.Context, cluster v1alpha1.Cluster) (<-chan event.Event, error) {
-	clusterID := util.ClusterID(o.group, cluster.Name)
-	if len(cluster.Context) == 0 {
+func (o *Options) apply(ctx context.Context, cluster util.TargetCluster) (<-chan event.Event, error) {
+	clusterID := cluster.ID()
+	if len(cluster.Cluster.Context) == 0 {
 		return nil, fmt.Errorf(applyErrorFormat, clusterID, errors.New("no context found"))
 	}
 
-	factory, err := o.factoryFor(ctx, clusterID, cluster.Context)
+	factory, err := o.factoryFor(ctx, clusterID, cluster.Cluster.Context)
+	if err != nil {
+		return nil, fmt.Errorf(applyErrorFormat, clusterID, err)
+	}
+
+	eventCh, err := o.applyManifests(ctx, factory, cluster)
 	if err != nil {
 		return nil, fmt.Errorf(applyErrorFormat, clusterID, err)
 	}
 
-	return o.applyManifests(ctx, factory, cluster.Name)
+	return eventCh, nil
 }
 
 // factoryFor return a rest.Config for connecting to the clusterID with context name
@@ -252,9 +390,9 @@ func (o *Options) factoryFor(ctx context.Context, clusterID, kubeContext string)
 	return factory, nil
 }
 
-func (o *Options) applyManifests(ctx context.Context, factory jplutil.ClientFactory, clusterName string) (<-chan event.Event, error) {
-	path := filepath.Join(o.contextPath, util.ClusterPath(o.group, clusterName))
-	clusterLogger := o.logger.WithName(util.ClusterID(o.group, clusterName))
+func (o *Options) applyManifests(ctx context.Context, factory jplutil.ClientFactory, cluster util.TargetCluster) (<-chan event.Event, error) {
+	path := filepath.Join(o.contextPath, cluster.Path())
+	clusterLogger := o.logger.WithName(cluster.ID())
 
 	clusterLogger.V(2).Info("reading manifests", "path", path)
 	manifests, err := readManifests(factory, path)
@@ -16,10 +16,13 @@
 package apply
 
 import (
+	"bytes"
 	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -28,7 +31,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	flowcontrolapi "k8s.io/api/flowcontrol/v1beta3"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
 	"k8s.io/client-go/rest/fake"
 
 	"github.com/mia-platform/vab/pkg/cmd/util"
@@ -61,6 +66,18 @@ func TestFlagsToOptions(t *testing.T) {
 			args:          []string{"first", filepath.Join("/", "invalid", "path")},
 			expectedError: filepath.Join("/", "invalid", "path"),
 		},
+		"all flag with group argument return error": {
+			flags:         &Flags{all: true, timeout: timeoutDefaultValue},
+			configFlags:   util.NewConfigFlags(),
+			args:          []string{"first", tmpDir},
+			expectedError: "accepts only the CONTEXT argument when targeting all groups",
+		},
+		"invalid concurrency return error": {
+			flags:         &Flags{timeout: timeoutDefaultValue, concurrency: 0},
+			configFlags:   util.NewConfigFlags(),
+			args:          []string{"first", tmpDir},
+			expectedError: "invalid concurrency 0: must be greater than zero",
+		},
 		"invalid timeout return error": {
 			flags:         &Flags{timeout: "invalid"},
 			configFlags:   util.NewConfigFlags(),
@@ -68,25 +85,42 @@ func TestFlagsToOptions(t *testing.T) {
 			expectedError: "failed to parse request timeout",
 		},
 		"two arguments": {
-			flags:       &Flags{timeout: timeoutDefaultValue},
+			flags:       &Flags{timeout: timeoutDefaultValue, concurrency: concurrencyDefaultValue},
 			configFlags: util.NewConfigFlags(),
 			args:        []string{"first", tmpDir},
 			expectedOptions: &Options{
 				fieldManager: "vab",
+				concurrency:  concurrencyDefaultValue,
 				group:        "first",
+				selector:     labels.Everything(),
 				contextPath:  tmpDir,
 				configPath:   "",
 			},
 		},
+		"all groups": {
+			flags:       &Flags{all: true, timeout: timeoutDefaultValue, concurrency: concurrencyDefaultValue},
+			configFlags: util.NewConfigFlags(),
+			args:        []string{tmpDir},
+			expectedOptions: &Options{
+				fieldManager: "vab",
+				concurrency:  concurrencyDefaultValue,
+				group:        util.AllGroupsPattern,
+				selector:     labels.Everything(),
+				contextPath:  tmpDir,
+			},
+		},
 		"three arguments": {
-			flags:       &Flags{timeout: timeoutDefaultValue, dryRun: true},
+			flags:       &Flags{timeout: timeoutDefaultValue, dryRun: true, failFast: true, concurrency: 4},
 			configFlags: &util.ConfigFlags{ConfigPath: &configFile},
 			args:        []string{"first", "second", tmpDir},
 			expectedOptions: &Options{
 				fieldManager: "vab",
 				dryRun:       true,
+				failFast:     true,
+				concurrency:  4,
 				group:        "first",
 				cluster:      "second",
+				selector:     labels.Everything(),
 				contextPath:  tmpDir,
 				configPath:   configFile,
 			},
@@ -97,7 +131,7 @@ func TestFlagsToOptions(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
-			opts, err := test.flags.ToOptions(test.configFlags, test.args)
+			opts, err := test.flags.ToOptions(test.configFlags, test.args, io.Discard)
 			if len(test.expectedError) > 0 {
 				assert.ErrorContains(t, err, test.expectedError)
 				assert.Nil(t, opts)
@@ -110,6 +144,8 @@ func TestFlagsToOptions(t *testing.T) {
 			assert.NotNil(t, opts.factoryAndConfigFunc)
 			// remove function to allow easy comparison between objects
 			opts.factoryAndConfigFunc = nil
+			assert.Equal(t, io.Discard, opts.writer)
+			opts.writer = nil
 			assert.Equal(t, test.expectedOptions, opts)
 		})
 	}
@@ -123,7 +159,9 @@ func TestApplyRun(t *testing.T) {
 	tests := map[string]struct {
 		options                  *Options
 		client                   *fake.RESTClient
+		cancelContext            bool
 		expectedError            string
+		expectedOutput           []string
 		returnErrorInLocalServer bool
 	}{
 		"missing group in config return error": {
@@ -170,6 +208,25 @@ func TestApplyRun(t *testing.T) {
 			returnErrorInLocalServer: true,
 			expectedError:            `applying resources for "test-group2/test-cluster": flowcontrol api`,
 		},
+		"errors on every cluster of the group are aggregated": {
+			options: &Options{
+				group:       "test-group2",
+				contextPath: testdata,
+				configPath:  configPath,
+			},
+			returnErrorInLocalServer: true,
+			expectedError:            `apply failed for 2 of 2 clusters ["test-group2/test-cluster" "test-group2/test-cluster2"]`,
+		},
+		"fail fast stop at first failing cluster": {
+			options: &Options{
+				group:       "test-group2",
+				failFast:    true,
+				contextPath: testdata,
+				configPath:  configPath,
+			},
+			returnErrorInLocalServer: true,
+			expectedError:            `apply failed for 1 of 2 clusters ["test-group2/test-cluster"], skipped for ["test-group2/test-cluster2"]`,
+		},
 		"invalid context path return error": {
 			options: &Options{
 				group:       "test-group2",
@@ -181,17 +238,71 @@ func TestApplyRun(t *testing.T) {
 		},
 		"successful apply": {
 			options: &Options{
+				dryRun:      true,
 				group:       "test-group2",
 				cluster:     "test-cluster",
 				contextPath: testdata,
 				configPath:  configPath,
 			},
 			client: &fake.RESTClient{
-				Client: fake.CreateHTTPClient(func(*http.Request) (*http.Response, error) {
-					return &http.Response{StatusCode: http.StatusOK, Header: jpltesting.DefaultHeaders()}, nil
+				NegotiatedSerializer: resource.UnstructuredPlusDefaultContentConfig().NegotiatedSerializer,
+				Client: fake.CreateHTTPClient(func(r *http.Request) (*http.Response, error) {
+					if r.Method == http.MethodGet {
+						return &http.Response{StatusCode: http.StatusNotFound, Header: jpltesting.DefaultHeaders()}, nil
+					}
+					return &http.Response{StatusCode: http.StatusOK, Header: jpltesting.DefaultHeaders(), Body: r.Body}, nil
 				}),
 			},
 		},
+		"successful apply of entire group": {
+			options: &Options{
+				dryRun:      true,
+				group:       "test-group2",
+				contextPath: testdata,
+				configPath:  configPath,
+			},
+			client: &fake.RESTClient{
+				NegotiatedSerializer: resource.UnstructuredPlusDefaultContentConfig().NegotiatedSerializer,
+				Client: fake.CreateHTTPClient(func(r *http.Request) (*http.Response, error) {
+					if r.Method == http.MethodGet {
+						return &http.Response{StatusCode: http.StatusNotFound, Header: jpltesting.DefaultHeaders()}, nil
+					}
+					return &http.Response{StatusCode: http.StatusOK, Header: jpltesting.DefaultHeaders(), Body: r.Body}, nil
+				}),
+			},
+		},
+		"parallel apply of entire group": {
+			options: &Options{
+				dryRun:      true,
+				concurrency: 2,
+				group:       "test-group2",
+				contextPath: testdata,
+				configPath:  configPath,
+			},
+			client: &fake.RESTClient{
+				NegotiatedSerializer: resource.UnstructuredPlusDefaultContentConfig().NegotiatedSerializer,
+				Client: fake.CreateHTTPClient(func(r *http.Request) (*http.Response, error) {
+					if r.Method == http.MethodGet {
+						return &http.Response{StatusCode: http.StatusNotFound, Header: jpltesting.DefaultHeaders()}, nil
+					}
+					return &http.Response{StatusCode: http.StatusOK, Header: jpltesting.DefaultHeaders(), Body: r.Body}, nil
+				}),
+			},
+			expectedOutput: []string{
+				"test-group2/test-cluster: Service test: applied successfully\n",
+				"test-group2/test-cluster2: Service test: applied successfully\n",
+			},
+		},
+		"cancelled context skip every cluster": {
+			options: &Options{
+				concurrency: 2,
+				group:       "test-group2",
+				contextPath: testdata,
+				configPath:  configPath,
+			},
+			cancelContext: true,
+			expectedError: context.Canceled.Error(),
+		},
 		// "": {},
 	}
 
@@ -201,6 +312,9 @@ func TestApplyRun(t *testing.T) {
 
 			ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
 			defer cancel()
+			if test.cancelContext {
+				cancel()
+			}
 
 			var server *httptest.Server
 			server = httptest.NewServer(http.HandlerFunc(func(r http.ResponseWriter, _ *http.Request) {
@@ -214,23 +328,75 @@ func TestApplyRun(t *testing.T) {
 			}))
 			defer server.Close()
 
-			factory := jpltesting.NewTestClientFactory()
-			factory.Client = test.client
-			restConfig, err := factory.ToRESTConfig()
-			require.NoError(t, err)
-			restConfig.Host = server.URL
-
+			// every cluster get its own factory because the fake client is not safe for concurrent use
 			test.options.factoryAndConfigFunc = func(string) (jplutil.ClientFactory, *genericclioptions.ConfigFlags) {
+				factory := jpltesting.NewTestClientFactory()
+				if test.client != nil {
+					client := *test.client
+					factory.Client = &client
+				}
+				restConfig, err := factory.ToRESTConfig()
+				require.NoError(t, err)
+				restConfig.Host = server.URL
 				return factory, genericclioptions.NewConfigFlags(false)
 			}
 
-			err = test.options.Run(ctx)
+			buffer := new(bytes.Buffer)
+			test.options.writer = buffer
+
+			err := test.options.Run(ctx)
 			switch len(test.expectedError) {
 			case 0:
 				assert.NoError(t, err)
 			default:
 				assert.ErrorContains(t, err, test.expectedError)
 			}
+
+			for _, line := range test.expectedOutput {
+				assert.Contains(t, buffer.String(), line)
+			}
 		})
 	}
 }
+
+func TestFailFastCancelsRunningClusters(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+
+	// the first cluster reaching the server fails, the other one is applying until it is cancelled
+	requests := new(atomic.Int32)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			require.NoError(t, err)
+			conn.Close()
+			return
+		}
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	options := &Options{
+		group:       "test-group2",
+		failFast:    true,
+		concurrency: 2,
+		contextPath: "testdata",
+		configPath:  filepath.Join("testdata", "testconfig.yaml"),
+		writer:      io.Discard,
+		factoryAndConfigFunc: func(string) (jplutil.ClientFactory, *genericclioptions.ConfigFlags) {
+			factory := jpltesting.NewTestClientFactory()
+			restConfig, err := factory.ToRESTConfig()
+			require.NoError(t, err)
+			restConfig.Host = server.URL
+			return factory, genericclioptions.NewConfigFlags(false)
+		},
+	}
+
+	err := options.Run(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "apply failed for 1 of 2 clusters")
+	assert.Contains(t, err.Error(), "cancelled for")
+	assert.NotContains(t, err.Error(), "skipped for")
+}
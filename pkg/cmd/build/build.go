@@ -25,6 +25,8 @@ import (
 	"github.com/MakeNowJust/heredoc/v2"
 	"github.com/go-logr/logr"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/labels"
 
 	"github.com/mia-platform/vab/pkg/cmd/util"
 )
@@ -37,21 +39,46 @@ const (
 	allowing the user to check if all the resources are generated correctly for
 	the target cluster.
 
-	The configurations will be searched inside the path passed as context`
-	cmdUsage = "build GROUP [CLUSTER] CONTEXT"
+	The configurations will be searched inside the path passed as context.
 
-	minArgs = 2
+	GROUP and CLUSTER accept glob patterns (e.g. "prod-*") for targeting multiple
+	groups or clusters at once, while the --all flag will target every cluster of
+	every group found in the configuration file.
+
+	The --selector flag filters the targeted clusters using the labels set on them
+	and on their groups (e.g. "env=prod,region!=eu").`
+	cmdUsage = "build [--all | GROUP [CLUSTER]] CONTEXT"
+
+	allDefaultValue = false
+	allFlagName     = "all"
+	allUsage        = "if true build every cluster of every group in the configuration"
+
+	selectorFlagName      = "selector"
+	selectorFlagShortName = "l"
+	selectorUsage         = "label selector to filter the targeted clusters (e.g. env=prod,region!=eu)"
+
+	minArgs = 1
 	maxArgs = 3
 )
 
 // Flags contains all the flags for the `build` command. They will be converted to Options
 // that contains all runtime options for the command
-type Flags struct{}
+type Flags struct {
+	all      bool
+	selector string
+}
+
+// AddFlags set the connection between Flags property to command line flags
+func (f *Flags) AddFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&f.all, allFlagName, allDefaultValue, heredoc.Doc(allUsage))
+	flags.StringVarP(&f.selector, selectorFlagName, selectorFlagShortName, "", heredoc.Doc(selectorUsage))
+}
 
 // Options have the data required to perform the apply operation
 type Options struct {
 	group       string
 	cluster     string
+	selector    labels.Selector
 	contextPath string
 	configPath  string
 	writer      io.Writer
@@ -76,16 +103,15 @@ func NewCommand(cf *util.ConfigFlags) *cobra.Command {
 		},
 	}
 
+	flags.AddFlags(cmd.Flags())
 	return cmd
 }
 
 // ToOptions transform the command flags in command runtime arguments
 func (f *Flags) ToOptions(cf *util.ConfigFlags, args []string, writer io.Writer) (*Options, error) {
-	group := args[0]
-	cluster := ""
-	contextPath := args[len(args)-1]
-	if len(args) >= maxArgs {
-		cluster = args[1]
+	group, cluster, contextPath, err := util.ParseTargetArgs(args, f.all)
+	if err != nil {
+		return nil, err
 	}
 
 	cleanedContextPath, err := util.ValidateContextPath(contextPath)
@@ -93,6 +119,11 @@ func (f *Flags) ToOptions(cf *util.ConfigFlags, args []string, writer io.Writer)
 		return nil, err
 	}
 
+	selector, err := util.ParseSelector(f.selector)
+	if err != nil {
+		return nil, err
+	}
+
 	configPath := ""
 	if cf.ConfigPath != nil && len(*cf.ConfigPath) > 0 {
 		configPath = filepath.Clean(*cf.ConfigPath)
@@ -101,6 +132,7 @@ func (f *Flags) ToOptions(cf *util.ConfigFlags, args []string, writer io.Writer)
 	return &Options{
 		group:       group,
 		cluster:     cluster,
+		selector:    selector,
 		contextPath: cleanedContextPath,
 		configPath:  configPath,
 		writer:      writer,
@@ -111,23 +143,16 @@ func (f *Flags) ToOptions(cf *util.ConfigFlags, args []string, writer io.Writer)
 func (o *Options) Run(ctx context.Context) error {
 	o.logger = logr.FromContextOrDiscard(ctx)
 
-	group, err := util.GroupFromConfig(o.group, o.configPath)
+	clusters, err := util.ClustersFromConfig(o.group, o.cluster, o.selector, o.configPath)
 	if err != nil {
 		return err
 	}
 
-	found := false
 	str := new(strings.Builder)
-	for _, cluster := range group.Clusters {
-		clusterName := cluster.Name
-		if o.cluster != "" && clusterName != o.cluster {
-			continue
-		}
-
-		found = true
-		path := filepath.Join(o.contextPath, util.ClusterPath(o.group, clusterName))
+	for _, cluster := range clusters {
+		path := filepath.Join(o.contextPath, cluster.Path())
 
-		clusterID := util.ClusterID(o.group, clusterName)
+		clusterID := cluster.ID()
 		str.WriteString("---\n")
 		fmt.Fprintf(str, "### BUILD RESULTS FOR: %q ###\n", clusterID)
 		o.logger.V(5).Info("loading resources", "cluster", clusterID)
@@ -137,13 +162,6 @@ func (o *Options) Run(ctx context.Context) error {
 		o.logger.V(9).Info("end loading resources", "cluster", clusterID)
 	}
 
-	switch {
-	case !found && len(o.cluster) == 0:
-		return fmt.Errorf("group %q doesn't have any cluster", o.group)
-	case !found && len(o.cluster) != 0:
-		return fmt.Errorf("group %q doesn't have cluster %q", o.group, o.cluster)
-	}
-
 	fmt.Fprint(o.writer, str.String())
 	return nil
 }
@@ -21,6 +21,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/labels"
 
 	"github.com/mia-platform/vab/pkg/cmd/util"
 )
@@ -95,6 +96,60 @@ spec:
   type: ClusterIP
 `,
 		},
+		"build clusters matching pattern": {
+			options: &Options{
+				group:       "test-*",
+				cluster:     "test-cluster2",
+				contextPath: testdata,
+				configPath:  configFile,
+			},
+			expectedOutput: `---
+### BUILD RESULTS FOR: "test-group2/test-cluster2" ###
+apiVersion: v1
+kind: Service
+metadata:
+  name: test
+spec:
+  ports:
+  - port: 80
+    protocol: TCP
+    targetPort: 80
+  selector:
+    app: test
+  type: ClusterIP
+`,
+		},
+		"build clusters matching selector": {
+			options: &Options{
+				group:       util.AllGroupsPattern,
+				selector:    labels.SelectorFromSet(labels.Set{"env": "test", "region": "eu"}),
+				contextPath: testdata,
+				configPath:  configFile,
+			},
+			expectedOutput: `---
+### BUILD RESULTS FOR: "test-group2/test-cluster2" ###
+apiVersion: v1
+kind: Service
+metadata:
+  name: test
+spec:
+  ports:
+  - port: 80
+    protocol: TCP
+    targetPort: 80
+  selector:
+    app: test
+  type: ClusterIP
+`,
+		},
+		"build all groups stop at first failing cluster": {
+			options: &Options{
+				group:       util.AllGroupsPattern,
+				contextPath: testdata,
+				configPath:  configFile,
+			},
+			expectedError: `building resources for "test-group/test-cluster":`,
+		},
 		"build entire group": {
 			options: &Options{
 				group:       "test-group2",
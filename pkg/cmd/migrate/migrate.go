@@ -0,0 +1,108 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha2"
+	"github.com/mia-platform/vab/pkg/cmd/util"
+)
+
+const (
+	shortCmd = "Migrate the configuration file to the newest API version"
+	longCmd  = `Rewrite the configuration file with the newest API version, converting the modules
+	and add-ons of every group and cluster to its format.
+
+	The file is edited in place keeping its comments and ordering; a file that already
+	uses the newest API version is left untouched.`
+
+	upToDateMessage = "The configuration file already uses " + v1alpha2.Version
+	migratedMessage = "The configuration file has been migrated to " + v1alpha2.Version
+)
+
+// Options have the data required to perform the migrate operation
+type Options struct {
+	configPath string
+	writer     io.Writer
+	logger     logr.Logger
+}
+
+// NewCommand return the command for migrating the configuration file to the newest API version
+func NewCommand(cf *util.ConfigFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: heredoc.Doc(shortCmd),
+		Long:  heredoc.Doc(longCmd),
+
+		Args: cobra.NoArgs,
+
+		Run: func(cmd *cobra.Command, _ []string) {
+			options := ToOptions(cf, cmd.OutOrStdout())
+			cobra.CheckErr(options.Run(cmd.Context()))
+		},
+	}
+
+	return cmd
+}
+
+// ToOptions transform the command flags in command runtime arguments
+func ToOptions(cf *util.ConfigFlags, writer io.Writer) *Options {
+	configPath := ""
+	if cf.ConfigPath != nil && len(*cf.ConfigPath) > 0 {
+		configPath = filepath.Clean(*cf.ConfigPath)
+	}
+
+	return &Options{
+		configPath: configPath,
+		writer:     writer,
+	}
+}
+
+// Run execute the migrate command
+func (o *Options) Run(ctx context.Context) error {
+	o.logger = logr.FromContextOrDiscard(ctx)
+
+	editor, err := util.OpenConfigEditor(o.configPath)
+	if err != nil {
+		return err
+	}
+
+	migrated, err := editor.Migrate()
+	if err != nil {
+		return err
+	}
+
+	if !migrated {
+		fmt.Fprintln(o.writer, upToDateMessage)
+		return nil
+	}
+
+	o.logger.V(5).Info("writing config file", "path", o.configPath)
+	if err := editor.Save(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(o.writer, migratedMessage)
+	return nil
+}
@@ -0,0 +1,78 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migrate
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mia-platform/vab/pkg/cmd/util"
+)
+
+func TestCommand(t *testing.T) {
+	t.Parallel()
+
+	cmd := NewCommand(util.NewConfigFlags())
+	assert.NotNil(t, cmd)
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	configFlags := util.NewTestConfigFlags(t, filepath.Join("testdata", "config.yaml"))
+	configPath := *configFlags.ConfigPath
+	buffer := new(bytes.Buffer)
+
+	require.NoError(t, ToOptions(configFlags, buffer).Run(t.Context()))
+	data, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, `# configuration used for testing the migration
+kind: ClustersConfiguration
+apiVersion: vab.mia-platform.eu/v1alpha2
+name: test
+spec:
+  modules:
+    ingress/traefik:
+      flavor: base
+      version: 1.20.1 # pinned by the platform team
+  addOns:
+    monitoring/traefik:
+      version: 1.20.1
+  groups:
+  - name: group
+    clusters:
+    - name: cluster
+      context: context
+      modules:
+        ingress/traefik:
+          flavor: ha
+          version: 1.20.1
+`, string(data))
+
+	require.NoError(t, ToOptions(configFlags, buffer).Run(t.Context()))
+	assert.Equal(t, `The configuration file has been migrated to vab.mia-platform.eu/v1alpha2
+The configuration file already uses vab.mia-platform.eu/v1alpha2
+`, buffer.String())
+
+	missingPath := filepath.Join(t.TempDir(), "missing.yaml")
+	configFlags.ConfigPath = &missingPath
+	assert.ErrorContains(t, ToOptions(configFlags, buffer).Run(t.Context()), "no such file or directory")
+}
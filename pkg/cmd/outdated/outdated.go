@@ -0,0 +1,141 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outdated
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+
+	"github.com/mia-platform/vab/internal/git"
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
+	"github.com/mia-platform/vab/pkg/cmd/util"
+)
+
+const (
+	shortCmd = "List the packages with their newest versions available"
+	longCmd  = `List the modules and add-ons of the configuration file with the newest version
+	available in their repository, marking as outdated the ones that have a newer version.
+
+	Every package is checked in the scope where it is defined, so the overrides of groups
+	and clusters are reported separately. A version range is outdated only if the newest
+	version doesn't match it.`
+
+	outdatedStatus = "outdated"
+	upToDateStatus = "up-to-date"
+)
+
+// Flags contains all the flags for the `outdated` command. They will be converted to Options
+// that contains all runtime options for the command.
+type Flags struct{}
+
+// Options have the data required to perform the outdated operation
+type Options struct {
+	configPath  string
+	writer      io.Writer
+	filesGetter *git.FilesGetter
+	logger      logr.Logger
+}
+
+// NewCommand return the command for listing the packages with newer versions
+func NewCommand(cf *util.ConfigFlags) *cobra.Command {
+	flags := &Flags{}
+	cmd := &cobra.Command{
+		Use:   "outdated",
+		Short: heredoc.Doc(shortCmd),
+		Long:  heredoc.Doc(longCmd),
+
+		Args: cobra.NoArgs,
+
+		Run: func(cmd *cobra.Command, _ []string) {
+			options, err := flags.ToOptions(cf, cmd.OutOrStdout())
+			cobra.CheckErr(err)
+			cobra.CheckErr(options.Run(cmd.Context()))
+		},
+	}
+
+	return cmd
+}
+
+// ToOptions transform the command flags in command runtime arguments
+func (f *Flags) ToOptions(cf *util.ConfigFlags, writer io.Writer) (*Options, error) {
+	configPath := ""
+	if cf.ConfigPath != nil && len(*cf.ConfigPath) > 0 {
+		configPath = filepath.Clean(*cf.ConfigPath)
+	}
+
+	return &Options{
+		configPath:  configPath,
+		writer:      writer,
+		filesGetter: git.NewFilesGetter(),
+	}, nil
+}
+
+// Run execute the outdated command
+func (o *Options) Run(ctx context.Context) error {
+	o.logger = logr.FromContextOrDiscard(ctx)
+
+	config, err := util.ReadConfig(o.configPath)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	versionsCache := util.NewVersionsCache(o.filesGetter.ListVersions)
+	rows := make([]string, 0)
+	err = util.WalkPackages(config.Spec, func(ref util.PackageRef, scope string, pkg v1alpha1.Package) error {
+		if pkg.Disable || len(pkg.Version) == 0 {
+			return nil
+		}
+
+		if pkg.Source == (v1alpha1.Source{}) {
+			pkg.Source = config.Spec.Source
+		}
+
+		o.logger.V(5).Info("listing package versions", "scope", scope, "type", pkg.PackageType(), "name", pkg.GetName())
+		versions, err := versionsCache.Versions(pkg)
+		if err != nil {
+			return fmt.Errorf("listing versions of %s %s: %w", pkg.PackageType(), pkg.GetName(), err)
+		}
+
+		latest, err := util.LatestVersion(versions)
+		if err != nil {
+			return fmt.Errorf("finding latest version of %s %s: %w", pkg.PackageType(), pkg.GetName(), err)
+		}
+
+		status := upToDateStatus
+		if util.IsOutdated(pkg.Version, latest) {
+			status = outdatedStatus
+		}
+		rows = append(rows, fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s", scope, ref.Type, ref.Key, pkg.Version, latest, status))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(o.writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "SCOPE\tTYPE\tPACKAGE\tCURRENT\tLATEST\tSTATUS")
+	for _, row := range rows {
+		fmt.Fprintln(writer, row)
+	}
+	return writer.Flush()
+}
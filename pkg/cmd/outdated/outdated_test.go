@@ -0,0 +1,89 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outdated
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mia-platform/vab/internal/git"
+	"github.com/mia-platform/vab/pkg/cmd/util"
+)
+
+func TestCommand(t *testing.T) {
+	t.Parallel()
+
+	configFlags := util.NewConfigFlags()
+	cmd := NewCommand(configFlags)
+	assert.NotNil(t, cmd)
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	testdata := "testdata"
+	tests := map[string]struct {
+		configPath     string
+		expectedString string
+		expectedError  string
+	}{
+		"list packages": {
+			configPath: filepath.Join(testdata, "config.yaml"),
+			expectedString: `SCOPE          TYPE    PACKAGE                             CURRENT  LATEST  STATUS
+default        module  category/test-module1/test-flavor1  v1.0.0   v2.0.0  outdated
+default        addon   category/test-addon1                v2.0.0   v2.0.0  up-to-date
+group/cluster  module  category/test-module1/test-flavor2  ^1.0.0   v2.0.0  outdated
+group/cluster  addon   category/test-addon1                >=1.0.0  v2.0.0  up-to-date
+`,
+		},
+		"all packages up to date": {
+			configPath: filepath.Join(testdata, "up-to-date.yaml"),
+			expectedString: `SCOPE    TYPE    PACKAGE                             CURRENT  LATEST  STATUS
+default  module  category/test-module1/test-flavor1  v2.0.0   v2.0.0  up-to-date
+`,
+		},
+		"missing config file": {
+			configPath:    filepath.Join(testdata, "missing.yaml"),
+			expectedError: "no such file or directory",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			fg, _ := git.NewTestFilesGetter(t)
+			buffer := new(bytes.Buffer)
+			options := &Options{
+				configPath:  test.configPath,
+				writer:      buffer,
+				filesGetter: fg,
+			}
+
+			err := options.Run(t.Context())
+			if len(test.expectedError) > 0 {
+				assert.ErrorContains(t, err, test.expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedString, buffer.String())
+		})
+	}
+}
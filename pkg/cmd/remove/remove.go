@@ -0,0 +1,214 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remove
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/mia-platform/vab/internal/git"
+	"github.com/mia-platform/vab/pkg/cmd/util"
+)
+
+const (
+	shortCmd = "Remove packages, groups and clusters from the configuration file"
+	longCmd  = `Remove modules, add-ons, groups and clusters from the configuration file, and update
+	the clusters folders of the project for matching the new configuration.
+
+	The project is the folder containing the configuration file, and the file is edited
+	in place keeping its comments and ordering. The folders of the removed groups and
+	clusters are not deleted, because they can contain custom resources.`
+
+	shortPackageCmd = "Remove the %[1]s NAME from the configuration file"
+	longPackageCmd  = `Remove the %[1]s with NAME from the configuration file.
+
+	By default the %[1]s is removed from the ones installed on all the clusters; with the
+	--group and --cluster flags it is removed from a group or a cluster.`
+
+	shortGroupCmd = "Remove a group from the configuration file"
+	longGroupCmd  = `Remove the group with NAME and all its clusters from the configuration file.`
+
+	shortClusterCmd = "Remove a cluster from a group of the configuration file"
+	longClusterCmd  = `Remove the cluster with NAME of GROUP from the configuration file.`
+
+	groupFlagName   = "group"
+	groupUsage      = "the group where the package is removed"
+	clusterFlagName = "cluster"
+	clusterUsage    = "the cluster of the group where the package is removed"
+)
+
+// Flags contains all the flags for the `remove` subcommands. They will be converted to Options
+// that contains all runtime options for the command.
+type Flags struct {
+	group   string
+	cluster string
+}
+
+// AddPackageFlags set the connection between Flags property to command line flags for the package subcommands
+func (f *Flags) AddPackageFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&f.group, groupFlagName, "", heredoc.Doc(groupUsage))
+	flags.StringVar(&f.cluster, clusterFlagName, "", heredoc.Doc(clusterUsage))
+}
+
+// Options have the data required to perform the remove operation
+type Options struct {
+	configPath  string
+	contextPath string
+	edit        func(*util.ConfigEditor) error
+	filesGetter *git.FilesGetter
+	// unusedPath is the folder of the removed group or cluster, relative to contextPath
+	unusedPath string
+	writer     io.Writer
+}
+
+// NewCommand return the command for removing packages, groups and clusters from the configuration file
+func NewCommand(cf *util.ConfigFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "remove",
+		Short: heredoc.Doc(shortCmd),
+		Long:  heredoc.Doc(longCmd),
+
+		Args: cobra.NoArgs,
+	}
+
+	cmd.AddCommand(
+		newPackageCommand(cf, "module"),
+		newPackageCommand(cf, "addon"),
+		newGroupCommand(cf),
+		newClusterCommand(cf),
+	)
+	return cmd
+}
+
+func newPackageCommand(cf *util.ConfigFlags, pkgType string) *cobra.Command {
+	flags := &Flags{}
+	cmd := &cobra.Command{
+		Use:   pkgType + " NAME",
+		Short: heredoc.Docf(shortPackageCmd, pkgType),
+		Long:  heredoc.Docf(longPackageCmd, pkgType),
+
+		Args: cobra.ExactArgs(1),
+
+		Run: func(cmd *cobra.Command, args []string) {
+			options, err := flags.ToPackageOptions(cf, pkgType, args[0], cmd.OutOrStdout())
+			cobra.CheckErr(err)
+			cobra.CheckErr(options.Run(cmd.Context()))
+		},
+	}
+
+	flags.AddPackageFlags(cmd.Flags())
+	return cmd
+}
+
+func newGroupCommand(cf *util.ConfigFlags) *cobra.Command {
+	flags := &Flags{}
+	cmd := &cobra.Command{
+		Use:   "group NAME",
+		Short: heredoc.Doc(shortGroupCmd),
+		Long:  heredoc.Doc(longGroupCmd),
+
+		Args: cobra.ExactArgs(1),
+
+		Run: func(cmd *cobra.Command, args []string) {
+			options, err := flags.ToGroupOptions(cf, args[0], cmd.OutOrStdout())
+			cobra.CheckErr(err)
+			cobra.CheckErr(options.Run(cmd.Context()))
+		},
+	}
+
+	return cmd
+}
+
+func newClusterCommand(cf *util.ConfigFlags) *cobra.Command {
+	flags := &Flags{}
+	cmd := &cobra.Command{
+		Use:   "cluster GROUP NAME",
+		Short: heredoc.Doc(shortClusterCmd),
+		Long:  heredoc.Doc(longClusterCmd),
+
+		Args: cobra.ExactArgs(2),
+
+		Run: func(cmd *cobra.Command, args []string) {
+			options, err := flags.ToClusterOptions(cf, args[0], args[1], cmd.OutOrStdout())
+			cobra.CheckErr(err)
+			cobra.CheckErr(options.Run(cmd.Context()))
+		},
+	}
+
+	return cmd
+}
+
+// ToPackageOptions transform the command flags in runtime arguments for removing the package of pkgType with key
+func (f *Flags) ToPackageOptions(cf *util.ConfigFlags, pkgType, key string, writer io.Writer) (*Options, error) {
+	if len(f.cluster) > 0 && len(f.group) == 0 {
+		return nil, errors.New("the --cluster flag needs the --group flag")
+	}
+
+	scope := util.ConfigScope{Group: f.group, Cluster: f.cluster}
+	return newOptions(cf, writer, "", func(editor *util.ConfigEditor) error {
+		return editor.RemovePackage(scope, pkgType, key)
+	})
+}
+
+// ToGroupOptions transform the command flags in runtime arguments for removing the group with name
+func (f *Flags) ToGroupOptions(cf *util.ConfigFlags, name string, writer io.Writer) (*Options, error) {
+	return newOptions(cf, writer, filepath.Dir(util.GroupLayerPath(name)), func(editor *util.ConfigEditor) error {
+		return editor.RemoveGroup(name)
+	})
+}
+
+// ToClusterOptions transform the command flags in runtime arguments for removing the cluster with name of group
+func (f *Flags) ToClusterOptions(cf *util.ConfigFlags, group, name string, writer io.Writer) (*Options, error) {
+	return newOptions(cf, writer, util.ClusterPath(group, name), func(editor *util.ConfigEditor) error {
+		return editor.RemoveCluster(group, name)
+	})
+}
+
+// newOptions return the Options for applying edit to the configuration file, the project is the folder containing it
+func newOptions(cf *util.ConfigFlags, writer io.Writer, unusedPath string, edit func(*util.ConfigEditor) error) (*Options, error) {
+	configPath, contextPath, err := util.ProjectPaths(cf)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Options{
+		configPath:  configPath,
+		contextPath: contextPath,
+		edit:        edit,
+		filesGetter: git.NewFilesGetter(),
+		unusedPath:  unusedPath,
+		writer:      writer,
+	}, nil
+}
+
+// Run execute the remove command
+func (o *Options) Run(ctx context.Context) error {
+	if err := util.EditProject(ctx, o.configPath, o.contextPath, o.edit, o.filesGetter.ListVersions); err != nil {
+		return err
+	}
+
+	if len(o.unusedPath) > 0 {
+		fmt.Fprintf(o.writer, "the folder %s is no longer used and can be deleted\n", o.unusedPath)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remove
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mia-platform/vab/internal/git"
+	"github.com/mia-platform/vab/pkg/cmd/util"
+)
+
+func TestCommand(t *testing.T) {
+	t.Parallel()
+
+	cmd := NewCommand(util.NewConfigFlags())
+	assert.NotNil(t, cmd)
+	assert.Len(t, cmd.Commands(), 4)
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	configFlags := util.NewTestConfigFlags(t, filepath.Join("testdata", "config.yaml"))
+	contextPath := filepath.Dir(*configFlags.ConfigPath)
+	buffer := new(bytes.Buffer)
+
+	run := func(options *Options, err error) {
+		t.Helper()
+		require.NoError(t, err)
+		require.NoError(t, options.Run(t.Context()))
+	}
+
+	run((&Flags{group: "group", cluster: "cluster"}).ToPackageOptions(configFlags, "addon", "monitoring/traefik", buffer))
+	run((&Flags{}).ToPackageOptions(configFlags, "module", "ingress/traefik/base", buffer))
+	run((&Flags{}).ToClusterOptions(configFlags, "group", "cluster", buffer))
+	run((&Flags{}).ToGroupOptions(configFlags, "other-group", buffer))
+
+	data, err := os.ReadFile(*configFlags.ConfigPath)
+	require.NoError(t, err)
+	assert.Equal(t, `kind: ClustersConfiguration
+apiVersion: vab.mia-platform.eu/v1alpha1
+name: test
+spec:
+  modules: {}
+  addOns: {}
+  groups:
+  - name: group
+    clusters: []
+`, string(data))
+
+	assert.Equal(t, `the folder clusters/group/cluster is no longer used and can be deleted
+the folder clusters/other-group is no longer used and can be deleted
+`, buffer.String())
+	bases, err := os.ReadFile(filepath.Join(contextPath, "clusters", "all-groups", "bases", "kustomization.yaml"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(bases), "ingress/traefik")
+
+	options, err := (&Flags{}).ToPackageOptions(configFlags, "module", "ingress/traefik/base", buffer)
+	require.NoError(t, err)
+	assert.ErrorContains(t, options.Run(t.Context()), "module ingress/traefik/base not found in spec")
+
+	_, err = (&Flags{cluster: "cluster"}).ToPackageOptions(configFlags, "module", "ingress/traefik/base", buffer)
+	assert.ErrorContains(t, err, "the --cluster flag needs the --group flag")
+}
+
+func TestRunWithVersionRanges(t *testing.T) {
+	t.Parallel()
+
+	configFlags := util.NewTestConfigFlags(t, filepath.Join("testdata", "version-ranges.yaml"))
+	contextPath := filepath.Dir(*configFlags.ConfigPath)
+	options, err := (&Flags{}).ToPackageOptions(configFlags, "module", "ingress/traefik/base", new(bytes.Buffer))
+	require.NoError(t, err)
+	fg, _ := git.NewTestFilesGetter(t)
+	options.filesGetter = fg
+	require.NoError(t, options.Run(t.Context()))
+
+	bases, err := os.ReadFile(filepath.Join(contextPath, "clusters", "group", "cluster", "bases", "kustomization.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(bases), "monitoring/traefik-v1.1.0")
+	assert.NotContains(t, string(bases), "ingress/traefik")
+}
@@ -26,10 +26,16 @@ import (
 	"github.com/go-logr/stdr"
 	"github.com/spf13/cobra"
 
+	"github.com/mia-platform/vab/pkg/cmd/add"
 	"github.com/mia-platform/vab/pkg/cmd/apply"
 	"github.com/mia-platform/vab/pkg/cmd/build"
 	"github.com/mia-platform/vab/pkg/cmd/create"
+	"github.com/mia-platform/vab/pkg/cmd/migrate"
+	"github.com/mia-platform/vab/pkg/cmd/outdated"
+	"github.com/mia-platform/vab/pkg/cmd/remove"
+	"github.com/mia-platform/vab/pkg/cmd/schema"
 	"github.com/mia-platform/vab/pkg/cmd/sync"
+	"github.com/mia-platform/vab/pkg/cmd/upgrade"
 	"github.com/mia-platform/vab/pkg/cmd/util"
 	"github.com/mia-platform/vab/pkg/cmd/validate"
 )
@@ -80,6 +86,12 @@ func NewVabCommand() *cobra.Command {
 		build.NewCommand(configFlags),
 		validate.NewCommand(configFlags),
 		sync.NewCommand(configFlags),
+		outdated.NewCommand(configFlags),
+		upgrade.NewCommand(configFlags),
+		add.NewCommand(configFlags),
+		remove.NewCommand(configFlags),
+		migrate.NewCommand(configFlags),
+		schema.NewCommand(),
 	)
 	return cmd
 }
@@ -0,0 +1,126 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha2"
+)
+
+const (
+	shortCmd = "Print the JSON Schema of the configuration file"
+	longCmd  = `Print the JSON Schema of the configuration file, generated from the
+	ClustersConfiguration types of the newest apiVersion or of the one set with
+	the --api-version flag.
+
+	The schema can be used by editors with a YAML language server for completing
+	and checking the configuration file, for example saving it next to the file
+	and adding a modeline at its top:
+
+		vab schema > config.schema.json
+		# yaml-language-server: $schema=./config.schema.json`
+
+	apiVersionFlagName = "api-version"
+	apiVersionUsage    = "the apiVersion of the configuration whose schema is printed"
+)
+
+// schemas contains the JSON Schema of the configuration for every supported apiVersion
+var schemas = map[string][]byte{
+	v1alpha1.Version: v1alpha1.ClustersConfigurationSchema,
+	v1alpha2.Version: v1alpha2.ClustersConfigurationSchema,
+}
+
+// Flags contains all the flags for the `schema` command. They will be converted to Options
+// that contains all runtime options for the command.
+type Flags struct {
+	apiVersion string
+}
+
+// AddFlags set the connection between Flags property to command line flags
+func (f *Flags) AddFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&f.apiVersion, apiVersionFlagName, v1alpha2.Version, heredoc.Doc(apiVersionUsage))
+}
+
+// Options have the data required to perform the schema operation
+type Options struct {
+	apiVersion string
+	writer     io.Writer
+	logger     logr.Logger
+}
+
+// NewCommand return the command for printing the JSON Schema of the configuration file
+func NewCommand() *cobra.Command {
+	flags := &Flags{}
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: heredoc.Doc(shortCmd),
+		Long:  heredoc.Doc(longCmd),
+
+		Args:              cobra.NoArgs,
+		ValidArgsFunction: cobra.NoFileCompletions,
+
+		Run: func(cmd *cobra.Command, _ []string) {
+			options, err := flags.ToOptions(cmd.OutOrStdout())
+			cobra.CheckErr(err)
+			cobra.CheckErr(options.Run(cmd.Context()))
+		},
+	}
+
+	flags.AddFlags(cmd.Flags())
+	return cmd
+}
+
+// ToOptions transform the command flags in command runtime arguments
+func (f *Flags) ToOptions(writer io.Writer) (*Options, error) {
+	apiVersion := f.apiVersion
+	if len(apiVersion) == 0 {
+		apiVersion = v1alpha2.Version
+	}
+
+	if _, found := schemas[apiVersion]; !found {
+		return nil, fmt.Errorf("unsupported apiVersion %q: it must be one of %v", apiVersion, supportedVersions())
+	}
+
+	return &Options{
+		apiVersion: apiVersion,
+		writer:     writer,
+	}, nil
+}
+
+// Run execute the schema command
+func (o *Options) Run(ctx context.Context) error {
+	o.logger = logr.FromContextOrDiscard(ctx)
+
+	o.logger.V(5).Info("writing schema", "kind", v1alpha1.Kind, "apiVersion", o.apiVersion)
+	_, err := o.writer.Write(schemas[o.apiVersion])
+	return err
+}
+
+// supportedVersions return the sorted list of apiVersions with a schema
+func supportedVersions() []string {
+	return slices.Sorted(maps.Keys(schemas))
+}
@@ -0,0 +1,94 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha2"
+)
+
+func TestCommand(t *testing.T) {
+	t.Parallel()
+
+	cmd := NewCommand()
+	assert.NotNil(t, cmd)
+
+	buffer := new(bytes.Buffer)
+	cmd.SetOut(buffer)
+	cmd.SetArgs([]string{})
+	assert.NoError(t, cmd.Execute())
+	assert.Equal(t, string(v1alpha2.ClustersConfigurationSchema), buffer.String())
+}
+
+func TestSchema(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		apiVersion         string
+		expectedVersion    string
+		expectedDefinition string
+	}{
+		"default apiVersion": {
+			expectedVersion:    v1alpha2.Version,
+			expectedDefinition: "Module",
+		},
+		"v1alpha1": {
+			apiVersion:         v1alpha1.Version,
+			expectedVersion:    v1alpha1.Version,
+			expectedDefinition: "Package",
+		},
+		"v1alpha2": {
+			apiVersion:         v1alpha2.Version,
+			expectedVersion:    v1alpha2.Version,
+			expectedDefinition: "Module",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			buffer := new(bytes.Buffer)
+			options, err := (&Flags{apiVersion: test.apiVersion}).ToOptions(buffer)
+			require.NoError(t, err)
+			require.NoError(t, options.Run(t.Context()))
+
+			schema := make(map[string]any)
+			require.NoError(t, json.Unmarshal(buffer.Bytes(), &schema))
+			assert.Equal(t, "ClustersConfiguration", schema["title"])
+
+			properties, ok := schema["properties"].(map[string]any)
+			require.True(t, ok)
+			assert.Equal(t, v1alpha1.Kind, properties["kind"].(map[string]any)["const"])
+			assert.Equal(t, test.expectedVersion, properties["apiVersion"].(map[string]any)["const"])
+			assert.Contains(t, schema["$defs"], test.expectedDefinition)
+		})
+	}
+}
+
+func TestUnsupportedAPIVersion(t *testing.T) {
+	t.Parallel()
+
+	_, err := (&Flags{apiVersion: "vab.mia-platform.eu/v1"}).ToOptions(new(bytes.Buffer))
+	assert.ErrorContains(t, err, `unsupported apiVersion "vab.mia-platform.eu/v1": it must be one of [vab.mia-platform.eu/v1alpha1 vab.mia-platform.eu/v1alpha2]`)
+}
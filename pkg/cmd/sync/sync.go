@@ -17,14 +17,20 @@ package sync
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"sync/atomic"
 
 	"github.com/MakeNowJust/heredoc/v2"
 	"github.com/go-logr/logr"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/mia-platform/vab/internal/git"
 	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
@@ -38,23 +44,57 @@ const (
 
 	After the execution, the vendors folder will include the new and updated
 	modules/add-ons (if not already present), and the directory structure
-	inside the clusters folder will be updated according to the current configuration.`
+	inside the clusters folder will be updated according to the current configuration.
+
+	The downloaded packages are stored in a local cache and reused by the following
+	executions; with the --offline flag only the cache is used and the command fails
+	if a package is missing from it.
+
+	The repository, tag, commit and files hash of every package are recorded in the
+	vab.lock file; with the --frozen flag the lock file is not updated and the command
+	fails if a package doesn't match it.
+
+	The version of a package can be a range (e.g. ~1.20.0 or ^2.1), that is resolved
+	to the highest matching version found in the tags of its repository and recorded
+	in the lock file; in frozen or offline mode the recorded version is used.`
 	cmdUsage = "sync CONTEXT"
 
 	dryRunDefaultValue = true
 	dryRunFlagName     = "download-packages"
 	dryRunUsage        = "if false packages files will not be downloaded"
+
+	offlineFlagName = "offline"
+	offlineUsage    = "use only the packages found in the local cache, without contacting the remote repositories"
+
+	jobsDefaultValue = 4
+	jobsFlagName     = "jobs"
+	jobsShortName    = "j"
+	jobsUsage        = "the number of packages to download in parallel"
+
+	frozenFlagName = "frozen"
+	frozenUsage    = "fail if the packages don't match the ones recorded in the lock file, without updating it"
+
+	cacheDirFlagName = "cache-dir"
+	cacheDirUsage    = "path of the local cache for the packages, by default a folder inside the user cache directory"
 )
 
 // Flags contains all the flags for the `sync` command. They will be converted to Options
 // that contains all runtime options for the command.
 type Flags struct {
 	downloadPackages bool
+	offline          bool
+	frozen           bool
+	cacheDir         string
+	jobs             int
 }
 
 // AddFlags set the connection between Flags property to command line flags
 func (f *Flags) AddFlags(flags *pflag.FlagSet) {
 	flags.BoolVar(&f.downloadPackages, dryRunFlagName, dryRunDefaultValue, heredoc.Doc(dryRunUsage))
+	flags.BoolVar(&f.offline, offlineFlagName, false, heredoc.Doc(offlineUsage))
+	flags.BoolVar(&f.frozen, frozenFlagName, false, heredoc.Doc(frozenUsage))
+	flags.StringVar(&f.cacheDir, cacheDirFlagName, "", heredoc.Doc(cacheDirUsage))
+	flags.IntVarP(&f.jobs, jobsFlagName, jobsShortName, jobsDefaultValue, heredoc.Doc(jobsUsage))
 }
 
 // Options have the data required to perform the sync operation
@@ -62,6 +102,9 @@ type Options struct {
 	contextPath      string
 	configPath       string
 	downloadPackages bool
+	frozen           bool
+	offline          bool
+	jobs             int
 	filesGetter      *git.FilesGetter
 	logger           logr.Logger
 }
@@ -100,14 +143,39 @@ func (f *Flags) ToOptions(cf *util.ConfigFlags, args []string) (*Options, error)
 		return nil, err
 	}
 
+	if f.jobs < 1 {
+		return nil, fmt.Errorf("invalid jobs %d: must be greater than zero", f.jobs)
+	}
+
+	filesGetter, err := f.FilesGetter()
+	if err != nil {
+		return nil, err
+	}
+
 	return &Options{
 		contextPath:      contextPath,
 		configPath:       configPath,
 		downloadPackages: f.downloadPackages,
-		filesGetter:      git.NewFilesGetter(),
+		frozen:           f.frozen,
+		offline:          f.offline,
+		jobs:             f.jobs,
+		filesGetter:      filesGetter,
 	}, nil
 }
 
+// FilesGetter return the FilesGetter that use the local cache and the offline mode set with the flags
+func (f *Flags) FilesGetter() (*git.FilesGetter, error) {
+	cacheDir := f.cacheDir
+	if len(cacheDir) == 0 {
+		var err error
+		if cacheDir, err = git.DefaultCachePath(); err != nil {
+			return nil, err
+		}
+	}
+
+	return git.NewCachedFilesGetter(git.NewCache(filepath.Clean(cacheDir)), f.offline), nil
+}
+
 // Run execute the create command
 func (o *Options) Run(ctx context.Context) error {
 	o.logger = logr.FromContextOrDiscard(ctx)
@@ -117,15 +185,74 @@ func (o *Options) Run(ctx context.Context) error {
 		return fmt.Errorf("reading config file: %w", err)
 	}
 
+	lock, err := o.readLock()
+	if err != nil {
+		return err
+	}
+
+	ranges, err := o.resolveVersionRanges(&config.Spec, lock)
+	if err != nil {
+		return err
+	}
+
 	o.logger.V(5).Info("ensuring directories", "path", o.contextPath)
 	if err := util.SyncDirectories(config.Spec, o.contextPath); err != nil {
 		return err
 	}
 
-	return o.vendorPackages(config)
+	return o.vendorPackages(config, lock, ranges)
+}
+
+// readLock return the lock file of the project when it is needed: in frozen mode it must be present, while in
+// offline mode it is used only for the resolved ranges and it can be missing
+func (o *Options) readLock() (*v1alpha1.PackagesLock, error) {
+	if !o.frozen && !o.offline {
+		return v1alpha1.EmptyLock(), nil
+	}
+
+	lock, err := util.ReadLock(o.contextPath)
+	switch {
+	case err == nil:
+		return lock, nil
+	case o.frozen && o.downloadPackages:
+		return nil, fmt.Errorf("frozen mode needs a lock file: %w", err)
+	case errors.Is(err, fs.ErrNotExist):
+		return v1alpha1.EmptyLock(), nil
+	default:
+		return nil, err
+	}
+}
+
+// resolveVersionRanges replaces the version ranges of the enabled packages contained in spec with the resolved
+// versions, and return them. The versions recorded in lock are used first, then in frozen or offline mode the
+// missing ranges are an error, otherwise the highest version matching the range is searched in the tags of the
+// package repository
+func (o *Options) resolveVersionRanges(spec *v1alpha1.ConfigSpec, lock *v1alpha1.PackagesLock) ([]v1alpha1.LockedRange, error) {
+	listVersions := o.filesGetter.ListVersions
+	if o.frozen || o.offline {
+		listVersions = func(pkg v1alpha1.Package) ([]string, error) {
+			return nil, fmt.Errorf("range %q not found in lock file: run sync without --frozen and --offline for resolving it", pkg.Version)
+		}
+	}
+
+	resolve := util.LockedRangeResolver(lock, listVersions)
+	return util.ResolveVersionRanges(spec, func(pkg v1alpha1.Package) (string, error) {
+		version, err := resolve(pkg)
+		if err == nil {
+			o.logger.V(5).Info("resolved version range", "type", pkg.PackageType(), "name", pkg.GetName(), "range", pkg.Version, "version", version)
+		}
+		return version, err
+	})
 }
 
-func (o *Options) vendorPackages(config *v1alpha1.ClustersConfiguration) error {
+func (o *Options) vendorPackages(config *v1alpha1.ClustersConfiguration, lock *v1alpha1.PackagesLock, ranges []v1alpha1.LockedRange) error {
+	lockedPackages := make(map[string]v1alpha1.LockedPackage)
+	if o.frozen {
+		for _, locked := range lock.Packages {
+			lockedPackages[packageKey(locked.Type, locked.Name, locked.Version)] = locked
+		}
+	}
+
 	vendorsPath := []string{
 		filepath.Join(o.contextPath, util.VendoredModulePath("")),
 		filepath.Join(o.contextPath, util.VendoredAddOnPath("")),
@@ -143,54 +270,173 @@ func (o *Options) vendorPackages(config *v1alpha1.ClustersConfiguration) error {
 	}
 
 	mergedPackages := make(map[string]v1alpha1.Package)
-	addPackages := func(packages map[string]v1alpha1.Package) {
+	addPackages := func(packages map[string]v1alpha1.Package) error {
 		for _, pkg := range packages {
 			if pkg.Disable {
 				o.logger.V(5).Info("skipping disabled package", "package", pkg.GetName(), "type", pkg.PackageType())
 				continue
 			}
-			mergedPackages[pkg.GetName()+pkg.GetFlavorName()+"_"+pkg.Version] = pkg
+			if pkg.Source == (v1alpha1.Source{}) {
+				pkg.Source = config.Spec.Source
+			}
+			// all the flavors of a module are downloaded together, so we need only one of them, but the same
+			// version is vendored only once and cannot come from different sources
+			key := packageKey(pkg.PackageType(), pkg.GetName(), pkg.Version)
+			if merged, found := mergedPackages[key]; found && git.SourceLocation(merged) != git.SourceLocation(pkg) {
+				return fmt.Errorf("%s %s %s is used with different sources: %q and %q", pkg.PackageType(), pkg.GetName(), pkg.Version, git.SourceLocation(merged), git.SourceLocation(pkg))
+			}
+			mergedPackages[key] = pkg
 		}
+		return nil
 	}
 
-	addPackages(config.Spec.Modules)
-	addPackages(config.Spec.AddOns)
-
+	scopes := []map[string]v1alpha1.Package{config.Spec.Modules, config.Spec.AddOns}
 	for _, group := range config.Spec.Groups {
+		scopes = append(scopes, group.Modules, group.AddOns)
 		for _, cluster := range group.Clusters {
-			addPackages(cluster.Modules)
-			addPackages(cluster.AddOns)
+			scopes = append(scopes, cluster.Modules, cluster.AddOns)
+		}
+	}
+
+	for _, packages := range scopes {
+		if err := addPackages(packages); err != nil {
+			return err
 		}
 	}
 
-	return o.clonePackagesLocally(mergedPackages, o.contextPath, o.filesGetter)
+	if o.frozen {
+		for key, pkg := range mergedPackages {
+			if _, found := lockedPackages[key]; !found {
+				return fmt.Errorf("%s %s %s not found in lock file: run sync without --frozen for updating it", pkg.PackageType(), pkg.GetName(), pkg.Version)
+			}
+		}
+	}
+
+	locked, err := o.clonePackagesLocally(mergedPackages, lockedPackages, o.contextPath, o.filesGetter)
+	if err != nil || o.frozen {
+		return err
+	}
+
+	o.logger.V(5).Info("writing lock file", "path", filepath.Join(o.contextPath, util.LockFileName))
+	return util.WriteLock(o.contextPath, locked, ranges)
+}
+
+// packageKey return the key used for identifying a package with pkgType, name and version
+func packageKey(pkgType, name, version string) string {
+	return pkgType + "_" + name + "_" + version
 }
 
-// clonePackagesLocally download packages using filesGetter
-func (o *Options) clonePackagesLocally(packages map[string]v1alpha1.Package, path string, filesGetter *git.FilesGetter) error {
-	for _, pkg := range packages {
-		o.logger.V(2).Info("cloning package", "type", pkg.PackageType(), "name", pkg.GetName())
-		files, err := filesGetter.GetFilesForPackage(pkg)
+// clonePackagesLocally download packages using filesGetter running at most o.jobs downloads at the same time, and
+// return their locked revisions. When the packages have an entry in lockedPackages with the same key, the downloaded
+// revision must match it. All the packages are downloaded even if some of them fail, and the returned error lists
+// all the failures
+func (o *Options) clonePackagesLocally(packages map[string]v1alpha1.Package, lockedPackages map[string]v1alpha1.LockedPackage, path string, filesGetter *git.FilesGetter) ([]v1alpha1.LockedPackage, error) {
+	keys := slices.Sorted(maps.Keys(packages))
+	total := len(keys)
+	var completed atomic.Int32
+
+	errs := make([]error, total)
+	locked := make([]v1alpha1.LockedPackage, total)
+	group := new(errgroup.Group)
+	group.SetLimit(max(o.jobs, 1))
+	for idx, key := range keys {
+		pkg := packages[key]
+		group.Go(func() error {
+			pkgLogger := o.logger.WithValues("type", pkg.PackageType(), "name", pkg.GetName(), "version", pkg.Version)
+			lockedPackage, isLocked := lockedPackages[key]
+			var err error
+			locked[idx], err = o.clonePackageLocally(pkgLogger, pkg, lockedPackage, isLocked, path, filesGetter)
+			errs[idx] = err
+			pkgLogger.V(2).Info("package done", "progress", fmt.Sprintf("%d/%d", completed.Add(1), total), "failed", errs[idx] != nil)
+			return nil
+		})
+	}
+	_ = group.Wait()
+
+	failedPackages := make([]string, 0)
+	for idx, err := range errs {
 		if err != nil {
-			return fmt.Errorf("cloning packages for %s %s: %w", pkg.PackageType(), pkg.GetName(), err)
-		}
-		o.logger.V(10).Info("finish cloning package", "type", pkg.PackageType(), "name", pkg.GetName())
-
-		pkgName := pkg.GetName() + "-" + pkg.Version
-		var pkgPath string
-		if pkg.IsModule() {
-			pkgPath = util.VendoredModulePath(pkgName)
-		} else {
-			pkgPath = util.VendoredAddOnPath(pkgName)
+			pkg := packages[keys[idx]]
+			failedPackages = append(failedPackages, fmt.Sprintf("%s %s %s", pkg.PackageType(), pkg.GetName(), pkg.Version))
 		}
+	}
+
+	if len(failedPackages) > 0 {
+		return nil, fmt.Errorf("download failed for %d of %d packages %q:\n%w", len(failedPackages), total, failedPackages, errors.Join(errs...))
+	}
+
+	return locked, nil
+}
 
-		o.logger.V(5).Info("copying package on disk", "type", pkg.PackageType(), "name", pkg.GetName())
-		if err := o.writePackageToDisk(files, filepath.Join(path, pkgPath)); err != nil {
-			return fmt.Errorf("writing %s %s on disk: %w", pkg.PackageType(), pkg.GetName(), err)
+// clonePackageLocally download pkg using filesGetter and write its files in the vendors folder inside path,
+// returning the locked revision of the package. If isLocked is true, the remote and downloaded revisions must
+// match lockedPackage
+func (o *Options) clonePackageLocally(logger logr.Logger, pkg v1alpha1.Package, lockedPackage v1alpha1.LockedPackage, isLocked bool, path string, filesGetter *git.FilesGetter) (v1alpha1.LockedPackage, error) {
+	if isLocked {
+		logger.V(5).Info("checking remote revision against lock file")
+		revision, err := filesGetter.RemoteRevision(pkg)
+		if err != nil {
+			return v1alpha1.LockedPackage{}, fmt.Errorf("resolving remote revision for %s %s: %w", pkg.PackageType(), pkg.GetName(), err)
+		}
+		if err := checkLockedRevision(lockedPackage, revision); err != nil {
+			return v1alpha1.LockedPackage{}, fmt.Errorf("%s %s: %w", pkg.PackageType(), pkg.GetName(), err)
 		}
-		o.logger.V(10).Info("finish copying package on disk", "type", pkg.PackageType(), "name", pkg.GetName())
 	}
-	return nil
+
+	logger.V(2).Info("cloning package")
+	files, revision, err := filesGetter.GetFilesForPackage(pkg)
+	if err != nil {
+		return v1alpha1.LockedPackage{}, fmt.Errorf("cloning packages for %s %s: %w", pkg.PackageType(), pkg.GetName(), err)
+	}
+	logger.V(10).Info("finish cloning package")
+
+	hash, err := git.HashFiles(files)
+	if err != nil {
+		return v1alpha1.LockedPackage{}, fmt.Errorf("hashing files for %s %s: %w", pkg.PackageType(), pkg.GetName(), err)
+	}
+
+	locked := v1alpha1.LockedPackage{
+		Type:       pkg.PackageType(),
+		Name:       pkg.GetName(),
+		Version:    pkg.Version,
+		Repository: revision.Repository,
+		Tag:        revision.Tag,
+		Commit:     revision.Commit,
+		Hash:       hash,
+	}
+	if isLocked && locked != lockedPackage {
+		return v1alpha1.LockedPackage{}, fmt.Errorf("%s %s: downloaded files don't match the lock file", pkg.PackageType(), pkg.GetName())
+	}
+
+	pkgName := pkg.GetName() + "-" + pkg.Version
+	var pkgPath string
+	if pkg.IsModule() {
+		pkgPath = util.VendoredModulePath(pkgName)
+	} else {
+		pkgPath = util.VendoredAddOnPath(pkgName)
+	}
+
+	logger.V(5).Info("copying package on disk")
+	if err := o.writePackageToDisk(files, filepath.Join(path, pkgPath)); err != nil {
+		return v1alpha1.LockedPackage{}, fmt.Errorf("writing %s %s on disk: %w", pkg.PackageType(), pkg.GetName(), err)
+	}
+	logger.V(10).Info("finish copying package on disk")
+
+	return locked, nil
+}
+
+// checkLockedRevision return an error if revision doesn't match the one recorded in lockedPackage
+func checkLockedRevision(lockedPackage v1alpha1.LockedPackage, revision git.Revision) error {
+	switch {
+	case lockedPackage.Repository != revision.Repository:
+		return fmt.Errorf("repository %s doesn't match locked repository %s", revision.Repository, lockedPackage.Repository)
+	case lockedPackage.Tag != revision.Tag:
+		return fmt.Errorf("tag %s doesn't match locked tag %s", revision.Tag, lockedPackage.Tag)
+	case lockedPackage.Commit != revision.Commit:
+		return fmt.Errorf("tag %s points to commit %s instead of locked commit %s", revision.Tag, revision.Commit, lockedPackage.Commit)
+	default:
+		return nil
+	}
 }
 
 // writePackageToDisk writes the files in memory to the target path on disk
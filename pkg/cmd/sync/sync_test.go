@@ -22,8 +22,10 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/mia-platform/vab/internal/git"
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
 	"github.com/mia-platform/vab/pkg/cmd/util"
 )
 
@@ -43,6 +45,7 @@ func TestToOptions(t *testing.T) {
 	testCases := map[string]struct {
 		args             []string
 		downloadPackages bool
+		jobs             int
 		configPath       string
 		expectedOptions  *Options
 		expectedError    string
@@ -55,20 +58,29 @@ func TestToOptions(t *testing.T) {
 			args:             []string{tempDir},
 			configPath:       "custom.yaml",
 			downloadPackages: true,
+			jobs:             2,
 			expectedOptions: &Options{
 				contextPath:      tempDir,
 				downloadPackages: true,
+				jobs:             2,
 				configPath:       "custom.yaml",
 			},
 		},
 		"no config path": {
 			args: []string{tempDir},
+			jobs: 1,
 			expectedOptions: &Options{
 				contextPath:      tempDir,
 				downloadPackages: false,
+				jobs:             1,
 				configPath:       "",
 			},
 		},
+		"invalid jobs": {
+			args:          []string{tempDir},
+			jobs:          0,
+			expectedError: "invalid jobs 0: must be greater than zero",
+		},
 	}
 
 	for testName, testCase := range testCases {
@@ -77,6 +89,7 @@ func TestToOptions(t *testing.T) {
 
 			flags := Flags{
 				downloadPackages: testCase.downloadPackages,
+				jobs:             testCase.jobs,
 			}
 			configFlags := util.NewConfigFlags()
 			configFlags.ConfigPath = &testCase.configPath
@@ -109,6 +122,7 @@ func TestRun(t *testing.T) {
 				configPath:       configPath,
 				contextPath:      t.TempDir(),
 				downloadPackages: true,
+				jobs:             2,
 				filesGetter: func() *git.FilesGetter {
 					fg, _ := git.NewTestFilesGetter(t)
 					return fg
@@ -116,6 +130,45 @@ func TestRun(t *testing.T) {
 			},
 			expectedPaths: append(folderStruct, vendorStruct...),
 		},
+		"report all failed packages": {
+			options: &Options{
+				configPath:       filepath.Join("testdata", "missing-packages.yaml"),
+				contextPath:      t.TempDir(),
+				downloadPackages: true,
+				jobs:             2,
+				filesGetter: func() *git.FilesGetter {
+					fg, _ := git.NewTestFilesGetter(t)
+					return fg
+				}(),
+			},
+			expectedError: `download failed for 2 of 3 packages ["addon category/missing-addon v1.0.0" "module category/missing-module v1.0.0"]`,
+		},
+		"refuse modules with more flavors": {
+			options: &Options{
+				configPath:       filepath.Join("testdata", "conflicting-flavors.yaml"),
+				contextPath:      t.TempDir(),
+				downloadPackages: true,
+				jobs:             2,
+				filesGetter: func() *git.FilesGetter {
+					fg, _ := git.NewTestFilesGetter(t)
+					return fg
+				}(),
+			},
+			expectedError: `module "category/test-module1" has more than one flavor in spec.modules`,
+		},
+		"refuse packages with different sources": {
+			options: &Options{
+				configPath:       filepath.Join("testdata", "conflicting-sources.yaml"),
+				contextPath:      t.TempDir(),
+				downloadPackages: true,
+				jobs:             2,
+				filesGetter: func() *git.FilesGetter {
+					fg, _ := git.NewTestFilesGetter(t)
+					return fg
+				}(),
+			},
+			expectedError: `addon category/test-addon2 v1.0.0 is used with different sources: "https://github.com/mia-platform/distribution" and "https://example.com/fork.git//packages"`,
+		},
 		"don't clone packages": {
 			options: &Options{
 				configPath:       configPath,
@@ -148,6 +201,106 @@ func TestRun(t *testing.T) {
 	}
 }
 
+func TestFrozenRun(t *testing.T) {
+	t.Parallel()
+
+	newOptions := func(contextPath string, frozen bool) *Options {
+		fg, _ := git.NewTestFilesGetter(t)
+		return &Options{
+			configPath:       filepath.Join("testdata", "config.yaml"),
+			contextPath:      contextPath,
+			downloadPackages: true,
+			frozen:           frozen,
+			jobs:             1,
+			filesGetter:      fg,
+		}
+	}
+
+	contextPath := t.TempDir()
+	err := newOptions(contextPath, true).Run(t.Context())
+	assert.ErrorContains(t, err, "frozen mode needs a lock file")
+
+	require.NoError(t, newOptions(contextPath, false).Run(t.Context()))
+	lock, err := util.ReadLock(contextPath)
+	require.NoError(t, err)
+	require.Len(t, lock.Packages, 2)
+	assert.Equal(t, v1alpha1.LockedPackage{
+		Type:       "addon",
+		Name:       "category/test-addon2",
+		Version:    "v1.0.0",
+		Repository: "https://github.com/mia-platform/distribution",
+		Tag:        "addon-category-test-addon2-v1.0.0",
+		Commit:     git.TestCommit,
+		Hash:       lock.Packages[0].Hash,
+	}, lock.Packages[0])
+	assert.Equal(t, "module", lock.Packages[1].Type)
+
+	lockData, err := os.ReadFile(filepath.Join(contextPath, util.LockFileName))
+	require.NoError(t, err)
+	require.NoError(t, newOptions(contextPath, true).Run(t.Context()))
+	frozenLockData, err := os.ReadFile(filepath.Join(contextPath, util.LockFileName))
+	require.NoError(t, err)
+	assert.Equal(t, string(lockData), string(frozenLockData))
+
+	lock.Packages[0].Commit = "fedcba9876543210fedcba9876543210fedcba98"
+	require.NoError(t, util.WriteLock(contextPath, lock.Packages, lock.Ranges))
+	err = newOptions(contextPath, true).Run(t.Context())
+	assert.ErrorContains(t, err, "instead of locked commit fedcba9876543210fedcba9876543210fedcba98")
+
+	lock.Packages = lock.Packages[1:]
+	require.NoError(t, util.WriteLock(contextPath, lock.Packages, lock.Ranges))
+	err = newOptions(contextPath, true).Run(t.Context())
+	assert.ErrorContains(t, err, "addon category/test-addon2 v1.0.0 not found in lock file")
+}
+
+func TestVersionRangesRun(t *testing.T) {
+	t.Parallel()
+
+	newOptions := func(contextPath string, frozen bool) *Options {
+		fg, _ := git.NewTestFilesGetter(t)
+		return &Options{
+			configPath:       filepath.Join("testdata", "version-ranges.yaml"),
+			contextPath:      contextPath,
+			downloadPackages: true,
+			frozen:           frozen,
+			jobs:             1,
+			filesGetter:      fg,
+		}
+	}
+
+	contextPath := t.TempDir()
+	require.NoError(t, newOptions(contextPath, false).Run(t.Context()))
+	lock, err := util.ReadLock(contextPath)
+	require.NoError(t, err)
+	assert.Equal(t, []v1alpha1.LockedRange{
+		{Type: "addon", Name: "category/test-addon2", Range: "^1.0.0", Repository: "https://github.com/mia-platform/distribution", Version: "v1.1.0"},
+		{Type: "module", Name: "category/test-module1", Range: "~1.0.0", Repository: "https://github.com/mia-platform/distribution", Version: "v1.0.1"},
+	}, lock.Ranges)
+	require.Len(t, lock.Packages, 2)
+	assert.Equal(t, "v1.1.0", lock.Packages[0].Version)
+	assert.Equal(t, "addon-category-test-addon2-v1.1.0", lock.Packages[0].Tag)
+	assert.Equal(t, "v1.0.1", lock.Packages[1].Version)
+
+	assert.DirExists(t, filepath.Join(contextPath, "vendors", "addons", "category", "test-addon2-v1.1.0"))
+	assert.DirExists(t, filepath.Join(contextPath, "vendors", "modules", "category", "test-module1-v1.0.1"))
+	kustomization, err := os.ReadFile(filepath.Join(contextPath, "clusters", "group", "cluster", "bases", "kustomization.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(kustomization), "test-module1-v1.0.1/test-flavor2")
+
+	// frozen mode must use the locked version even if the remote has a newer one
+	lock.Ranges[0].Version = "v1.0.0"
+	lock.Packages[0].Version = "v1.0.0"
+	lock.Packages[0].Tag = "addon-category-test-addon2-v1.0.0"
+	require.NoError(t, util.WriteLock(contextPath, lock.Packages, lock.Ranges))
+	require.NoError(t, newOptions(contextPath, true).Run(t.Context()))
+	assert.DirExists(t, filepath.Join(contextPath, "vendors", "addons", "category", "test-addon2-v1.0.0"))
+
+	lock.Ranges = lock.Ranges[1:]
+	require.NoError(t, util.WriteLock(contextPath, lock.Packages, lock.Ranges))
+	err = newOptions(contextPath, true).Run(t.Context())
+	assert.ErrorContains(t, err, `resolving version of addon category/test-addon2: range "^1.0.0" not found in lock file`)
+}
+
 var (
 	folderStruct = []string{
 		".",
@@ -159,6 +312,12 @@ var (
 		"clusters/all-groups/custom-resources/kustomization.yaml",
 		"clusters/all-groups/kustomization.yaml",
 		"clusters/group",
+		"clusters/group/all-clusters",
+		"clusters/group/all-clusters/bases",
+		"clusters/group/all-clusters/bases/kustomization.yaml",
+		"clusters/group/all-clusters/custom-resources",
+		"clusters/group/all-clusters/custom-resources/kustomization.yaml",
+		"clusters/group/all-clusters/kustomization.yaml",
 		"clusters/group/cluster",
 		"clusters/group/cluster/bases",
 		"clusters/group/cluster/bases/kustomization.yaml",
@@ -168,6 +327,7 @@ var (
 	}
 
 	vendorStruct = []string{
+		"vab.lock",
 		"vendors",
 		"vendors/addons",
 		"vendors/addons/category",
@@ -0,0 +1,230 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package upgrade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/mia-platform/vab/internal/git"
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
+	"github.com/mia-platform/vab/pkg/cmd/sync"
+	"github.com/mia-platform/vab/pkg/cmd/util"
+)
+
+const (
+	shortCmd = "Upgrade the packages versions in the configuration file"
+	longCmd  = `Upgrade the version of the modules and add-ons in the configuration file to the
+	newest one available in their repository, and then sync the project.
+
+	If PACKAGE is set only the package with that name is upgraded, in every group and
+	cluster that defines its version; for a module the name can include the flavor for
+	upgrading only it. The --to flag set a specific version instead of the newest one.
+
+	The configuration file is edited in place keeping its comments and ordering; the
+	packages that use a version range are upgraded only with the --to flag, because
+	their version is resolved during the sync.`
+	cmdUsage = "upgrade [PACKAGE]"
+
+	toFlagName = "to"
+	toUsage    = "the version to set for PACKAGE instead of the newest one"
+
+	upToDateMessage = "All packages are up to date"
+)
+
+// Flags contains all the flags for the `upgrade` command. They will be converted to Options
+// that contains all runtime options for the command.
+type Flags struct {
+	to        string
+	syncFlags sync.Flags
+}
+
+// AddFlags set the connection between Flags property to command line flags
+func (f *Flags) AddFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&f.to, toFlagName, "", heredoc.Doc(toUsage))
+	f.syncFlags.AddFlags(flags)
+}
+
+// Options have the data required to perform the upgrade operation
+type Options struct {
+	configPath  string
+	packageName string
+	version     string
+	writer      io.Writer
+	filesGetter *git.FilesGetter
+	syncOptions *sync.Options
+	logger      logr.Logger
+}
+
+// NewCommand return the command for upgrading the versions of the packages in the configuration file
+func NewCommand(cf *util.ConfigFlags) *cobra.Command {
+	flags := &Flags{}
+	cmd := &cobra.Command{
+		Use:   cmdUsage,
+		Short: heredoc.Doc(shortCmd),
+		Long:  heredoc.Doc(longCmd),
+
+		Args: cobra.MaximumNArgs(1),
+
+		Run: func(cmd *cobra.Command, args []string) {
+			options, err := flags.ToOptions(cf, args, cmd.OutOrStdout())
+			cobra.CheckErr(err)
+			cobra.CheckErr(options.Run(cmd.Context()))
+		},
+	}
+
+	flags.AddFlags(cmd.Flags())
+	return cmd
+}
+
+// ToOptions transform the command flags in command runtime arguments, the project synced after the upgrade
+// is the folder containing the configuration file
+func (f *Flags) ToOptions(cf *util.ConfigFlags, args []string, writer io.Writer) (*Options, error) {
+	configPath := ""
+	if cf.ConfigPath != nil && len(*cf.ConfigPath) > 0 {
+		configPath = filepath.Clean(*cf.ConfigPath)
+	}
+
+	packageName := ""
+	if len(args) > 0 {
+		packageName = args[0]
+	}
+
+	if len(f.to) > 0 && len(packageName) == 0 {
+		return nil, errors.New("the --to flag needs a PACKAGE")
+	}
+
+	syncOptions, err := f.syncFlags.ToOptions(cf, []string{filepath.Dir(configPath)})
+	if err != nil {
+		return nil, err
+	}
+
+	filesGetter, err := f.syncFlags.FilesGetter()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Options{
+		configPath:  configPath,
+		packageName: packageName,
+		version:     f.to,
+		writer:      writer,
+		filesGetter: filesGetter,
+		syncOptions: syncOptions,
+	}, nil
+}
+
+// Run execute the upgrade command
+func (o *Options) Run(ctx context.Context) error {
+	o.logger = logr.FromContextOrDiscard(ctx)
+
+	config, err := util.ReadConfig(o.configPath)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	versionsCache := util.NewVersionsCache(o.filesGetter.ListVersions)
+	versions := make(map[util.PackageRef]string)
+	found := false
+	err = util.WalkPackages(config.Spec, func(ref util.PackageRef, scope string, pkg v1alpha1.Package) error {
+		if pkg.Disable || len(pkg.Version) == 0 {
+			return nil
+		}
+		if len(o.packageName) > 0 && o.packageName != pkg.GetName() && o.packageName != ref.Key {
+			return nil
+		}
+		found = true
+
+		if len(o.version) == 0 && util.IsVersionRange(pkg.Version) {
+			o.logger.V(5).Info("skipping version range", "scope", scope, "type", ref.Type, "name", ref.Key, "range", pkg.Version)
+			return nil
+		}
+
+		if pkg.Source == (v1alpha1.Source{}) {
+			pkg.Source = config.Spec.Source
+		}
+		available, err := versionsCache.Versions(pkg)
+		if err != nil {
+			return fmt.Errorf("listing versions of %s %s: %w", pkg.PackageType(), pkg.GetName(), err)
+		}
+
+		version, err := o.targetVersion(pkg, available)
+		if err != nil || version == pkg.Version {
+			return err
+		}
+
+		fmt.Fprintf(o.writer, "upgrading %s %s in %s from %s to %s\n", ref.Type, ref.Key, scope, pkg.Version, version)
+		versions[ref] = version
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(o.packageName) > 0 && !found {
+		return fmt.Errorf("package %q not found in configuration", o.packageName)
+	}
+
+	if len(versions) == 0 {
+		fmt.Fprintln(o.writer, upToDateMessage)
+		return nil
+	}
+
+	o.logger.V(5).Info("updating config file", "path", o.configPath)
+	editor, err := util.OpenConfigEditor(o.configPath)
+	if err != nil {
+		return err
+	}
+	editor.SetPackagesVersion(versions)
+	if err := editor.Save(); err != nil {
+		return err
+	}
+
+	if o.syncOptions == nil {
+		return nil
+	}
+	return o.syncOptions.Run(ctx)
+}
+
+// targetVersion return the version to set for pkg: the one requested with the --to flag if it is available,
+// or the newest available version if it is newer than the current one
+func (o *Options) targetVersion(pkg v1alpha1.Package, available []string) (string, error) {
+	if len(o.version) > 0 {
+		if !slices.Contains(available, o.version) {
+			return "", fmt.Errorf("version %s of %s %s not found in its repository", o.version, pkg.PackageType(), pkg.GetName())
+		}
+		return o.version, nil
+	}
+
+	latest, err := util.LatestVersion(available)
+	if err != nil {
+		return "", fmt.Errorf("finding latest version of %s %s: %w", pkg.PackageType(), pkg.GetName(), err)
+	}
+
+	if !util.IsOutdated(pkg.Version, latest) {
+		return pkg.Version, nil
+	}
+	return latest, nil
+}
@@ -0,0 +1,168 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package upgrade
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mia-platform/vab/internal/git"
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
+	"github.com/mia-platform/vab/pkg/cmd/util"
+)
+
+func TestCommand(t *testing.T) {
+	t.Parallel()
+
+	configFlags := util.NewConfigFlags()
+	cmd := NewCommand(configFlags)
+	assert.NotNil(t, cmd)
+}
+
+func TestToOptions(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	configFlags := util.NewConfigFlags()
+	configFlags.ConfigPath = &configPath
+
+	newFlags := func(to string) *Flags {
+		flags := &Flags{}
+		flags.AddFlags(pflag.NewFlagSet("test", pflag.ContinueOnError))
+		flags.to = to
+		return flags
+	}
+
+	options, err := newFlags("v1.1.0").ToOptions(configFlags, []string{"category/test-addon1"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, configPath, options.configPath)
+	assert.Equal(t, "category/test-addon1", options.packageName)
+	assert.Equal(t, "v1.1.0", options.version)
+	assert.NotNil(t, options.syncOptions)
+
+	_, err = newFlags("v1.1.0").ToOptions(configFlags, nil, nil)
+	assert.ErrorContains(t, err, "the --to flag needs a PACKAGE")
+}
+
+func TestOfflineRun(t *testing.T) {
+	t.Parallel()
+
+	flags := &Flags{}
+	flagSet := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.AddFlags(flagSet)
+	require.NoError(t, flagSet.Parse([]string{"--offline", "--cache-dir", t.TempDir()}))
+
+	configFlags := util.NewTestConfigFlags(t, filepath.Join("testdata", "config.yaml"))
+	options, err := flags.ToOptions(configFlags, nil, new(bytes.Buffer))
+	require.NoError(t, err)
+	assert.ErrorIs(t, options.Run(t.Context()), git.ErrOffline)
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		packageName      string
+		version          string
+		expectedString   string
+		expectedVersions map[string]string
+		expectedError    string
+	}{
+		"upgrade all packages": {
+			expectedString: "upgrading module category/test-module1/test-flavor1 in default from v1.0.0 to v2.0.0\n",
+			expectedVersions: map[string]string{
+				"default category/test-module1/test-flavor1":       "v2.0.0",
+				"default category/test-addon1":                     "v2.0.0",
+				"group category/test-module1/test-flavor1":         "",
+				"group/cluster category/test-module1/test-flavor2": "^1.0.0",
+				"group/cluster category/test-addon1":               ">=1.0.0",
+			},
+		},
+		"upgrade package to version": {
+			packageName: "category/test-module1",
+			version:     "v1.1.0",
+			expectedString: `upgrading module category/test-module1/test-flavor1 in default from v1.0.0 to v1.1.0
+upgrading module category/test-module1/test-flavor2 in group/cluster from ^1.0.0 to v1.1.0
+`,
+			expectedVersions: map[string]string{
+				"default category/test-module1/test-flavor1":       "v1.1.0",
+				"default category/test-addon1":                     "v2.0.0",
+				"group category/test-module1/test-flavor1":         "",
+				"group/cluster category/test-module1/test-flavor2": "v1.1.0",
+				"group/cluster category/test-addon1":               ">=1.0.0",
+			},
+		},
+		"package already up to date": {
+			packageName:    "category/test-addon1",
+			expectedString: "All packages are up to date\n",
+		},
+		"package not found": {
+			packageName:   "category/missing",
+			expectedError: `package "category/missing" not found in configuration`,
+		},
+		"version not found": {
+			packageName:   "category/test-module1/test-flavor1",
+			version:       "v9.0.0",
+			expectedError: "version v9.0.0 of module category/test-module1 not found in its repository",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			configPath := *util.NewTestConfigFlags(t, filepath.Join("testdata", "config.yaml")).ConfigPath
+
+			fg, _ := git.NewTestFilesGetter(t)
+			buffer := new(bytes.Buffer)
+			options := &Options{
+				configPath:  configPath,
+				packageName: test.packageName,
+				version:     test.version,
+				writer:      buffer,
+				filesGetter: fg,
+			}
+
+			err := options.Run(t.Context())
+			if len(test.expectedError) > 0 {
+				assert.ErrorContains(t, err, test.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedString, buffer.String())
+			if test.expectedVersions == nil {
+				return
+			}
+
+			config, err := util.ReadConfig(configPath)
+			require.NoError(t, err)
+			versions := make(map[string]string)
+			err = util.WalkPackages(config.Spec, func(ref util.PackageRef, scope string, pkg v1alpha1.Package) error {
+				versions[scope+" "+ref.Key] = pkg.Version
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedVersions, versions)
+		})
+	}
+}
@@ -16,7 +16,6 @@
 package util
 
 import (
-	"bytes"
 	"cmp"
 	"errors"
 	"fmt"
@@ -30,8 +29,17 @@ import (
 	yaml "sigs.k8s.io/yaml/goyaml.v3"
 
 	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha2"
 )
 
+// AllClustersDirName is the name of the folder containing the layer shared by all the clusters of a group,
+// a cluster cannot use it as its name
+const AllClustersDirName = "all-clusters"
+
+// AllGroupsDirName is the name of the folder containing the layer shared by all the groups,
+// a group cannot use it as its name
+const AllGroupsDirName = "all-groups"
+
 const (
 	defaultConfigFileName = "config.yaml"
 
@@ -51,28 +59,146 @@ const (
 )
 
 var (
-	allGroupsDirPath = filepath.Join(clustersDirName, "all-groups")
+	allGroupsDirPath = filepath.Join(clustersDirName, AllGroupsDirName)
 	modulesDirPath   = filepath.Join(vendorsDirName, "modules")
 	addOnsDirPath    = filepath.Join(vendorsDirName, "addons")
 )
 
-// ReadConfig reads a configuration file into a ClustersConfiguration struct
+// ReadConfig reads a configuration file into a ClustersConfiguration struct, merging the groups and the clusters
+// of the files it includes
 func ReadConfig(configPath string) (*v1alpha1.ClustersConfiguration, error) {
-	if len(configPath) == 0 {
-		configPath = defaultConfigFileName
-	}
+	config, _, err := ReadConfigWithPositions(configPath)
+	return config, err
+}
 
+// ReadConfigWithPositions reads a configuration file like ReadConfig, returning also the positions of its nodes
+// and of the ones of the files it includes
+func ReadConfigWithPositions(configPath string) (*v1alpha1.ClustersConfiguration, ConfigPositions, error) {
+	configPath = ConfigFilePath(configPath)
 	configFile, err := os.ReadFile(configPath)
 	if err != nil {
-		return nil, fmt.Errorf("reading config file: %w", err)
+		return nil, nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	document := &yaml.Node{}
+	if err := yaml.Unmarshal(configFile, document); err != nil {
+		return nil, nil, fmt.Errorf("reading config file: %w", err)
+	}
+	includes, err := readIncludes(document, configPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading config file: %w", err)
+	}
+	errs, positions := checkConfigDocument(document, includes)
+	if len(errs) > 0 {
+		return nil, nil, fmt.Errorf("reading config file: %w", errs)
 	}
 
-	output := &v1alpha1.ClustersConfiguration{}
-	if err := yaml.Unmarshal(configFile, output); err != nil {
-		return nil, fmt.Errorf("reading config file: %w", err)
+	output, err := decodeConfigDocument(includes.merge(document))
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading config file: %w", err)
 	}
 
-	return output, nil
+	return output, positions, nil
+}
+
+// decodeConfigDocument decodes the yaml document of a configuration, converting it to v1alpha1 if it uses
+// a newer apiVersion
+func decodeConfigDocument(document *yaml.Node) (*v1alpha1.ClustersConfiguration, error) {
+	if documentAPIVersion(document) == v1alpha2.Version {
+		config := &v1alpha2.ClustersConfiguration{}
+		if err := document.Decode(config); err != nil {
+			return nil, err
+		}
+		return v1alpha2.ConvertToV1alpha1(config), nil
+	}
+
+	config := &v1alpha1.ClustersConfiguration{}
+	if err := document.Decode(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// documentAPIVersion return the apiVersion set in the yaml document of a configuration, or an empty string
+func documentAPIVersion(document *yaml.Node) string {
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 {
+		return ""
+	}
+
+	if apiVersion := mappingValue(document.Content[0], apiVersionKey); apiVersion != nil && apiVersion.Kind == yaml.ScalarNode {
+		return apiVersion.Value
+	}
+	return ""
+}
+
+// ConfigFilePath return configPath, or the path of the default configuration file if it is empty
+func ConfigFilePath(configPath string) string {
+	if len(configPath) == 0 {
+		return defaultConfigFileName
+	}
+	return configPath
+}
+
+// PackageRef identifies a package definition inside the configuration file
+type PackageRef struct {
+	// Group is the index of the group defining the package, or -1 for the packages of the spec
+	Group int
+	// Cluster is the index of the cluster of Group defining the package, or -1 for the packages of the group
+	Cluster int
+	// Type of the package, module or addon
+	Type string
+	// Key of the package in the configuration file, it includes the flavor for the modules
+	Key string
+}
+
+// NewPackageRef return the PackageRef for pkg defined in the cluster and group with the provided indexes
+func NewPackageRef(group, cluster int, pkg v1alpha1.Package) PackageRef {
+	key := pkg.GetName()
+	if pkg.IsModule() {
+		key += "/" + pkg.GetFlavorName()
+	}
+
+	return PackageRef{Group: group, Cluster: cluster, Type: pkg.PackageType(), Key: key}
+}
+
+// WalkPackages call fn for every package defined in spec with its reference inside the configuration file and the
+// name of the scope defining it. The packages are visited in the spec, group and cluster order, and inside every
+// scope the modules come before the add-ons, sorted by key
+func WalkPackages(spec v1alpha1.ConfigSpec, fn func(ref PackageRef, scope string, pkg v1alpha1.Package) error) error {
+	walkScope := func(group, cluster int, scope string, packagesMaps ...map[string]v1alpha1.Package) error {
+		for _, packages := range packagesMaps {
+			refs := make(map[PackageRef]v1alpha1.Package, len(packages))
+			for _, pkg := range packages {
+				refs[NewPackageRef(group, cluster, pkg)] = pkg
+			}
+
+			sortedRefs := slices.SortedFunc(maps.Keys(refs), func(a, b PackageRef) int { return cmp.Compare(a.Key, b.Key) })
+			for _, ref := range sortedRefs {
+				if err := fn(ref, scope, refs[ref]); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if err := walkScope(-1, -1, "default", spec.Modules, spec.AddOns); err != nil {
+		return err
+	}
+
+	for groupIdx, group := range spec.Groups {
+		if err := walkScope(groupIdx, -1, group.Name, group.Modules, group.AddOns); err != nil {
+			return err
+		}
+		for clusterIdx, cluster := range group.Clusters {
+			scope := ClusterID(group.Name, cluster.Name)
+			if err := walkScope(groupIdx, clusterIdx, scope, cluster.Modules, cluster.AddOns); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
 }
 
 // InitializeConfiguration will create an empty configuration file at path and then create all the folder
@@ -93,13 +219,25 @@ func InitializeConfiguration(name, path string) error {
 // SyncDirectories will create all the folders and kustomization files needed by the config data, it will leave
 // alone already present file in the custom-resources folder if they already exists, it will override everything else
 func SyncDirectories(config v1alpha1.ConfigSpec, path string) error {
-	if err := ensureFolderContent(path, allGroupsDirPath, config.Modules, config.AddOns); err != nil {
+	if err := ensureFolderContent(path, allGroupsDirPath, config.Modules, config.AddOns, nil); err != nil {
 		return err
 	}
 
-	addons := config.AddOns
-	modules := config.Modules
 	for _, group := range config.Groups {
+		modules := config.Modules
+		addons := config.AddOns
+		var layerModules, layerAddOns map[string]v1alpha1.Package
+		if len(group.Modules) != 0 || len(group.AddOns) != 0 {
+			modules = mergePackages(modules, group.Modules)
+			addons = mergePackages(addons, group.AddOns)
+			layerModules, layerAddOns = modules, addons
+		}
+
+		groupLayerPath := GroupLayerPath(group.Name)
+		if err := ensureFolderContent(path, groupLayerPath, layerModules, layerAddOns, []string{allGroupsDirPath}); err != nil {
+			return err
+		}
+
 		for _, cluster := range group.Clusters {
 			var clusterModules, clusterAddOns map[string]v1alpha1.Package
 			if len(cluster.Modules) != 0 || len(cluster.AddOns) != 0 {
@@ -108,7 +246,8 @@ func SyncDirectories(config v1alpha1.ConfigSpec, path string) error {
 			}
 
 			clusterPath := ClusterPath(group.Name, cluster.Name)
-			if err := ensureFolderContent(path, clusterPath, clusterModules, clusterAddOns); err != nil {
+			parentLayers := []string{allGroupsDirPath, groupLayerPath}
+			if err := ensureFolderContent(path, clusterPath, clusterModules, clusterAddOns, parentLayers); err != nil {
 				return err
 			}
 		}
@@ -119,8 +258,9 @@ func SyncDirectories(config v1alpha1.ConfigSpec, path string) error {
 
 // ensureFolderContent will create the folder structure if needed and create/override the contents of
 // the kustomization file under the bases folder, and ensure the presence of the custom-resource folder
-// with its kustomization file if they don't exists
-func ensureFolderContent(basePath string, clusterPath string, modules, addOns map[string]v1alpha1.Package) error {
+// with its kustomization file if they don't exists. parentLayers contains the paths of the layers that the folder
+// inherits from, ordered from the outermost one
+func ensureFolderContent(basePath string, clusterPath string, modules, addOns map[string]v1alpha1.Package, parentLayers []string) error {
 	path := filepath.Join(basePath, clusterPath)
 	name := filepath.Base(path)
 	basesDir := filepath.Join(path, basesDirName)
@@ -146,11 +286,14 @@ func ensureFolderContent(basePath string, clusterPath string, modules, addOns ma
 
 	sortedModules := sortedPackagesPath(basesDir, filepath.Join(basePath, modulesDirPath), modules)
 	sortedAddons := sortedPackagesPath(basesDir, filepath.Join(basePath, addOnsDirPath), addOns)
-	switch {
-	case len(sortedModules) == 0 && clusterPath != allGroupsDirPath:
-		sortedModules = append(sortedModules, relativeModulePath(basesDir, filepath.Join(basePath, allGroupsDirPath)))
-	case len(sortedAddons) > 0 && clusterPath != allGroupsDirPath:
-		sortedAddons = append(sortedAddons, relativeModulePath(basesDir, filepath.Join(basePath, allGroupsDirPath, customResourcesDirName)))
+	if len(sortedModules) == 0 && len(parentLayers) > 0 {
+		sortedModules = append(sortedModules, relativeModulePath(basesDir, filepath.Join(basePath, parentLayers[len(parentLayers)-1])))
+	} else {
+		// the folder does not build on top of its parent layers, so it must keep their custom resources
+		// even if it doesn't override any addon
+		for _, layer := range parentLayers {
+			sortedAddons = append(sortedAddons, relativeModulePath(basesDir, filepath.Join(basePath, layer, customResourcesDirName)))
+		}
 	}
 
 	// write bases file
@@ -246,19 +389,17 @@ func mergePackages(first, second map[string]v1alpha1.Package) map[string]v1alpha
 
 // writeYamlFile marshals the interface passed as argument, and writes it to a YAML file
 func writeYamlFile(path string, data interface{}) error {
-	buffer := new(bytes.Buffer)
-	encoder := yaml.NewEncoder(buffer)
-	encoder.SetIndent(yamlFileIndentation)
-	encoder.CompactSeqIndent()
-
-	if err := encoder.Encode(data); err != nil {
-		return err
-	}
-	if err := encoder.Close(); err != nil {
+	encodedData, err := encodeYaml(data)
+	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(path, buffer.Bytes(), filePermission)
+	return os.WriteFile(path, encodedData, filePermission)
+}
+
+// encodeYaml marshals the interface passed as argument with the indentation used for all the files written by vab
+func encodeYaml(data interface{}) ([]byte, error) {
+	return defaultYAMLStyle.encode(data)
 }
 
 // relativeModulePath return the relative path of a module to basePath from targetPath
@@ -0,0 +1,564 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	yaml "sigs.k8s.io/yaml/goyaml.v3"
+
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha2"
+)
+
+const (
+	specKey       = "spec"
+	groupsKey     = "groups"
+	clustersKey   = "clusters"
+	nameKey       = "name"
+	contextKey    = "context"
+	versionKey    = "version"
+	disableKey    = "disable"
+	sourceKey     = "source"
+	flavorKey     = "flavor"
+	apiVersionKey = "apiVersion"
+
+	stringTag = "!!str"
+	boolTag   = "!!bool"
+	nullTag   = "!!null"
+)
+
+// packagesKeys contains the keys of the packages dictionaries for every package type
+var packagesKeys = map[string]string{
+	"module": "modules",
+	"addon":  "addOns",
+}
+
+// ConfigScope identifies the part of the configuration defining packages: the spec if Group is empty,
+// the group if Cluster is empty, or a cluster of the group
+type ConfigScope struct {
+	Group   string
+	Cluster string
+}
+
+// ConfigEditor modifies a configuration file working on its yaml document, so the comments, the order
+// of the properties and the formatting of the parts that are not changed are kept when it is saved.
+// The packages are always referenced with the v1alpha1 keys, that include the flavor for the modules, and
+// they are written following the apiVersion of the document.
+// The groups and the clusters of the included files are edited in their own files
+type ConfigEditor struct {
+	path     string
+	document *yaml.Node
+	file     *yamlFile
+	includes *configIncludes
+}
+
+// OpenConfigEditor return a ConfigEditor for the configuration file at configPath
+func OpenConfigEditor(configPath string) (*ConfigEditor, error) {
+	configPath = ConfigFilePath(configPath)
+	configFile, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	document := new(yaml.Node)
+	if err := yaml.Unmarshal(configFile, document); err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("reading config file: the file doesn't contain a configuration")
+	}
+
+	file, err := newYAMLFile(configFile, document)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	includes, err := readIncludes(document, configPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	return &ConfigEditor{path: configPath, document: document, file: file, includes: includes}, nil
+}
+
+// Config return the configuration contained in the edited document and in the files it includes
+func (e *ConfigEditor) Config() (*v1alpha1.ClustersConfiguration, error) {
+	if errs, _ := checkConfigDocument(e.document, e.includes); len(errs) > 0 {
+		return nil, fmt.Errorf("decoding config: %w", errs)
+	}
+
+	config, err := decodeConfigDocument(e.includes.merge(e.document))
+	if err != nil {
+		return nil, fmt.Errorf("decoding config: %w", err)
+	}
+
+	return config, nil
+}
+
+// Bytes return the content of the configuration file with the edits made to its document
+func (e *ConfigEditor) Bytes() ([]byte, error) {
+	data, _, err := e.file.content(e.document)
+	return data, err
+}
+
+// Save writes the edits made to the document in the configuration file it was read from, changing only the
+// lines of the edited nodes, and the edited included files
+func (e *ConfigEditor) Save() error {
+	if err := e.file.save(e.path, e.document); err != nil {
+		return fmt.Errorf("writing config file: %w", err)
+	}
+
+	return e.includes.save()
+}
+
+// SetPackage adds the package of pkgType with key to scope, or updates it if already present, with the version,
+// disable flag and source of pkg
+func (e *ConfigEditor) SetPackage(scope ConfigScope, pkgType, key string, pkg v1alpha1.Package) error {
+	packagesKey, err := packagesKeyForType(pkgType, key)
+	if err != nil {
+		return err
+	}
+
+	scopeNode, err := e.scopeNode(scope)
+	if err != nil {
+		return err
+	}
+
+	nodeKey, flavor, flavorInKey := e.packageNodeKey(pkgType, key)
+	pkgNode := ensureMappingValue(ensureMappingValue(scopeNode, packagesKey, yaml.MappingNode), nodeKey, yaml.MappingNode)
+	if !flavorInKey {
+		setMappingValue(pkgNode, flavorKey, &yaml.Node{Kind: yaml.ScalarNode, Tag: stringTag, Value: flavor})
+	}
+	if len(pkg.Version) > 0 {
+		setMappingValue(pkgNode, versionKey, &yaml.Node{Kind: yaml.ScalarNode, Tag: stringTag, Value: pkg.Version})
+	} else {
+		removeMappingValue(pkgNode, versionKey)
+	}
+
+	if pkg.Disable {
+		setMappingValue(pkgNode, disableKey, &yaml.Node{Kind: yaml.ScalarNode, Tag: boolTag, Value: "true"})
+	} else {
+		removeMappingValue(pkgNode, disableKey)
+	}
+
+	if pkg.Source != (v1alpha1.Source{}) {
+		sourceNode := new(yaml.Node)
+		if err := sourceNode.Encode(pkg.Source); err != nil {
+			return fmt.Errorf("encoding source of %s %s: %w", pkgType, key, err)
+		}
+		setMappingValue(pkgNode, sourceKey, sourceNode)
+	} else {
+		removeMappingValue(pkgNode, sourceKey)
+	}
+
+	return nil
+}
+
+// RemovePackage removes the package of pkgType with key from scope
+func (e *ConfigEditor) RemovePackage(scope ConfigScope, pkgType, key string) error {
+	packagesKey, err := packagesKeyForType(pkgType, key)
+	if err != nil {
+		return err
+	}
+
+	scopeNode, err := e.scopeNode(scope)
+	if err != nil {
+		return err
+	}
+
+	packages := mappingValue(scopeNode, packagesKey)
+	nodeKey, flavor, flavorInKey := e.packageNodeKey(pkgType, key)
+	// a module without flavor can only disable the inherited one, so it matches every flavor
+	if pkgNode := mappingValue(packages, nodeKey); !flavorInKey && pkgNode != nil && len(nodeFlavor(pkgNode)) > 0 && nodeFlavor(pkgNode) != flavor {
+		return fmt.Errorf("%s %s not found in %s", pkgType, key, scope)
+	}
+	if !removeMappingValue(packages, nodeKey) {
+		return fmt.Errorf("%s %s not found in %s", pkgType, key, scope)
+	}
+
+	return nil
+}
+
+// Migrate converts the document to the newest apiVersion of the configuration, keeping its comments, and
+// return false if it already uses it. The modules keys are stripped of their flavor, that is moved in the
+// flavor property of the module
+func (e *ConfigEditor) Migrate() (bool, error) {
+	switch apiVersion := documentAPIVersion(e.document); apiVersion {
+	case v1alpha2.Version:
+		return false, nil
+	case v1alpha1.Version:
+	default:
+		return false, fmt.Errorf("unsupported apiVersion %q: it must be %s or %s", apiVersion, v1alpha1.Version, v1alpha2.Version)
+	}
+
+	if _, err := e.Config(); err != nil {
+		return false, err
+	}
+
+	scopes := []*yaml.Node{mappingValue(e.document.Content[0], specKey)}
+	for _, group := range e.includes.groupNodes(e.document) {
+		scopes = append(scopes, group)
+		scopes = append(scopes, e.includes.clusterNodes(group)...)
+	}
+
+	for _, scope := range scopes {
+		if err := migrateModules(mappingValue(scope, packagesKeys["module"])); err != nil {
+			return false, err
+		}
+	}
+
+	setMappingValue(e.document.Content[0], apiVersionKey, &yaml.Node{Kind: yaml.ScalarNode, Tag: stringTag, Value: v1alpha2.Version})
+	return true, nil
+}
+
+// SetPackagesVersion set the versions of the packages referenced in versions, the packages without a version
+// or not found are ignored
+func (e *ConfigEditor) SetPackagesVersion(versions map[PackageRef]string) {
+	flavorsInKeys := e.flavorsInKeys()
+	setScopeVersions(mappingValue(e.document.Content[0], specKey), -1, -1, versions, flavorsInKeys)
+	for groupIdx, group := range e.includes.groupNodes(e.document) {
+		setScopeVersions(group, groupIdx, -1, versions, flavorsInKeys)
+		for clusterIdx, cluster := range e.includes.clusterNodes(group) {
+			setScopeVersions(cluster, groupIdx, clusterIdx, versions, flavorsInKeys)
+		}
+	}
+}
+
+// AddGroup appends a new empty group with name to the configuration
+func (e *ConfigEditor) AddGroup(name string) error {
+	if len(name) == 0 {
+		return errors.New("group name cannot be empty")
+	}
+	if group, _ := e.groupNode(name); group != nil {
+		return fmt.Errorf("group %q already exists", name)
+	}
+
+	groups := ensureMappingValue(e.specNode(), groupsKey, yaml.SequenceNode)
+	group := &yaml.Node{Kind: yaml.MappingNode}
+	setMappingValue(group, nameKey, &yaml.Node{Kind: yaml.ScalarNode, Tag: stringTag, Value: name})
+	appendSequenceValue(groups, group)
+	return nil
+}
+
+// RemoveGroup removes the group with name and all its clusters from the configuration
+func (e *ConfigEditor) RemoveGroup(name string) error {
+	group, idx := e.groupNode(name)
+	if group == nil {
+		return fmt.Errorf("group %q not found", name)
+	}
+	if file := e.includes.file(group); file != nil {
+		return fmt.Errorf("group %q is defined in the included file %s: delete it or remove it from the include list", name, file.path)
+	}
+
+	groups := mappingValue(e.specNode(), groupsKey)
+	groups.Content = slices.Delete(groups.Content, idx, idx+1)
+	return nil
+}
+
+// AddCluster appends a new cluster with name and context to the group with groupName
+func (e *ConfigEditor) AddCluster(groupName, name, context string) error {
+	if len(name) == 0 {
+		return errors.New("cluster name cannot be empty")
+	}
+
+	group, _ := e.groupNode(groupName)
+	if group == nil {
+		return fmt.Errorf("group %q not found", groupName)
+	}
+	if cluster, _ := e.clusterNode(group, name); cluster != nil {
+		return fmt.Errorf("cluster %q already exists in group %q", name, groupName)
+	}
+
+	clusters := ensureMappingValue(group, clustersKey, yaml.SequenceNode)
+	cluster := &yaml.Node{Kind: yaml.MappingNode}
+	setMappingValue(cluster, nameKey, &yaml.Node{Kind: yaml.ScalarNode, Tag: stringTag, Value: name})
+	if len(context) > 0 {
+		setMappingValue(cluster, contextKey, &yaml.Node{Kind: yaml.ScalarNode, Tag: stringTag, Value: context})
+	}
+	appendSequenceValue(clusters, cluster)
+	return nil
+}
+
+// RemoveCluster removes the cluster with name from the group with groupName
+func (e *ConfigEditor) RemoveCluster(groupName, name string) error {
+	group, _ := e.groupNode(groupName)
+	if group == nil {
+		return fmt.Errorf("group %q not found", groupName)
+	}
+
+	cluster, idx := e.clusterNode(group, name)
+	if cluster == nil {
+		return fmt.Errorf("cluster %q not found in group %q", name, groupName)
+	}
+	if file := e.includes.file(cluster); file != nil {
+		return fmt.Errorf("cluster %q of group %q is defined in the included file %s: delete it or remove it from the include list", name, groupName, file.path)
+	}
+
+	clusters := mappingValue(group, clustersKey)
+	clusters.Content = slices.Delete(clusters.Content, idx, idx+1)
+	return nil
+}
+
+// String return a readable description of the scope
+func (s ConfigScope) String() string {
+	switch {
+	case len(s.Group) == 0:
+		return "spec"
+	case len(s.Cluster) == 0:
+		return "group " + s.Group
+	default:
+		return "cluster " + ClusterID(s.Group, s.Cluster)
+	}
+}
+
+// flavorsInKeys return true if the document uses an apiVersion that includes the flavor in the modules keys
+func (e *ConfigEditor) flavorsInKeys() bool {
+	return documentAPIVersion(e.document) != v1alpha2.Version
+}
+
+// packageNodeKey return the key of the node of the package of pkgType with the v1alpha1 key, and for the
+// apiVersions that don't include it in the key the flavor to set in the module
+func (e *ConfigEditor) packageNodeKey(pkgType, key string) (string, string, bool) {
+	if pkgType != "module" || e.flavorsInKeys() {
+		return key, "", true
+	}
+
+	name, flavor, _ := cutLast(key, "/")
+	return name, flavor, false
+}
+
+// nodeFlavor return the value of the flavor property of the module node
+func nodeFlavor(node *yaml.Node) string {
+	if flavor := mappingValue(node, flavorKey); flavor != nil {
+		return flavor.Value
+	}
+	return ""
+}
+
+// migrateModules moves the flavor contained in the keys of the modules mapping node to the flavor property
+// of the modules
+func migrateModules(modules *yaml.Node) error {
+	if modules == nil || modules.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for idx := 0; idx+1 < len(modules.Content); idx += 2 {
+		key, value := modules.Content[idx], modules.Content[idx+1]
+		if key.Tag == mergeTag {
+			continue
+		}
+		if value.Kind == yaml.AliasNode {
+			return fmt.Errorf("module %q is defined with an alias: replace it with its content before migrating", key.Value)
+		}
+
+		if strings.Count(key.Value, "/") < 2 {
+			return fmt.Errorf("module %q must be in the form category/name/flavor to be migrated", key.Value)
+		}
+
+		name, flavor, _ := cutLast(key.Value, "/")
+		if value.Kind == yaml.ScalarNode && value.Tag == nullTag {
+			*value = yaml.Node{Kind: yaml.MappingNode, HeadComment: value.HeadComment, LineComment: value.LineComment, FootComment: value.FootComment}
+		}
+		// the flavor is set as first property of the module, before its version
+		value.Content = slices.Insert(value.Content, 0,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: stringTag, Value: flavorKey},
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: stringTag, Value: flavor},
+		)
+		key.Value = name
+	}
+
+	return nil
+}
+
+// specNode return the spec of the configuration, creating it if missing
+func (e *ConfigEditor) specNode() *yaml.Node {
+	return ensureMappingValue(e.document.Content[0], specKey, yaml.MappingNode)
+}
+
+// groupNode return the group with name and its index, or nil if not found. The included groups follow
+// the ones defined in the document
+func (e *ConfigEditor) groupNode(name string) (*yaml.Node, int) {
+	return findByName(e.includes.groupNodes(e.document), name)
+}
+
+// clusterNode return the cluster with name inside group and its index, or nil if not found. The included
+// clusters follow the ones defined in the group
+func (e *ConfigEditor) clusterNode(group *yaml.Node, name string) (*yaml.Node, int) {
+	return findByName(e.includes.clusterNodes(group), name)
+}
+
+// scopeNode return the node of the spec, group or cluster identified by scope
+func (e *ConfigEditor) scopeNode(scope ConfigScope) (*yaml.Node, error) {
+	if len(scope.Group) == 0 {
+		return e.specNode(), nil
+	}
+
+	group, _ := e.groupNode(scope.Group)
+	if group == nil {
+		return nil, fmt.Errorf("group %q not found", scope.Group)
+	}
+	if len(scope.Cluster) == 0 {
+		return group, nil
+	}
+
+	cluster, _ := e.clusterNode(group, scope.Cluster)
+	if cluster == nil {
+		return nil, fmt.Errorf("cluster %q not found in group %q", scope.Cluster, scope.Group)
+	}
+	return cluster, nil
+}
+
+// packagesKeyForType return the key of the packages dictionary for pkgType, validating the package key
+func packagesKeyForType(pkgType, key string) (string, error) {
+	packagesKey, found := packagesKeys[pkgType]
+	switch {
+	case !found:
+		return "", fmt.Errorf("invalid package type %q", pkgType)
+	case len(key) == 0:
+		return "", fmt.Errorf("%s name cannot be empty", pkgType)
+	case pkgType == "module" && !strings.Contains(key, "/"):
+		return "", fmt.Errorf("module %q must include the flavor name", key)
+	}
+
+	return packagesKey, nil
+}
+
+// setScopeVersions set the version of the modules and add-ons contained in node that are found in versions,
+// if flavorsInKeys is false the keys of the modules are completed with their flavor property
+func setScopeVersions(node *yaml.Node, group, cluster int, versions map[PackageRef]string, flavorsInKeys bool) {
+	for pkgType, packagesKey := range packagesKeys {
+		packages := mappingValue(node, packagesKey)
+		if packages == nil || packages.Kind != yaml.MappingNode {
+			continue
+		}
+
+		for idx := 0; idx+1 < len(packages.Content); idx += 2 {
+			ref := PackageRef{Group: group, Cluster: cluster, Type: pkgType, Key: packages.Content[idx].Value}
+			if pkgType == "module" && !flavorsInKeys {
+				ref.Key += "/" + nodeFlavor(packages.Content[idx+1])
+			}
+			version, found := versions[ref]
+			if !found {
+				continue
+			}
+
+			if versionNode := mappingValue(packages.Content[idx+1], versionKey); versionNode != nil {
+				// force the string tag for avoiding that versions like 1.20 are read as numbers
+				versionNode.Tag = stringTag
+				versionNode.Value = version
+			}
+		}
+	}
+}
+
+// findByName return the mapping inside nodes with the name property set to name and its index
+func findByName(nodes []*yaml.Node, name string) (*yaml.Node, int) {
+	for idx, node := range nodes {
+		if nameNode := mappingValue(node, nameKey); nameNode != nil && nameNode.Value == name {
+			return node, idx
+		}
+	}
+
+	return nil, -1
+}
+
+// sequenceValues return the values of node if it is a sequence
+func sequenceValues(node *yaml.Node) []*yaml.Node {
+	if node == nil || node.Kind != yaml.SequenceNode {
+		return nil
+	}
+
+	return node.Content
+}
+
+// mappingValue return the value node of key if node is a mapping containing it, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		if node.Content[idx].Value == key {
+			return node.Content[idx+1]
+		}
+	}
+
+	return nil
+}
+
+// ensureMappingValue return the value node of key inside the mapping node, if it is missing or null a new
+// node of kind is set for it
+func ensureMappingValue(node *yaml.Node, key string, kind yaml.Kind) *yaml.Node {
+	value := mappingValue(node, key)
+	switch {
+	case value == nil:
+		value = &yaml.Node{Kind: kind}
+		setMappingValue(node, key, value)
+	case value.Kind == yaml.ScalarNode && value.Tag == nullTag:
+		*value = yaml.Node{Kind: kind, HeadComment: value.HeadComment, LineComment: value.LineComment, FootComment: value.FootComment}
+	}
+
+	return value
+}
+
+// setMappingValue set value for key inside the mapping node. An existing value keeps its comments, and it keeps
+// also its style if both are scalars. A new key is appended at the end of the mapping
+func setMappingValue(node *yaml.Node, key string, value *yaml.Node) {
+	if current := mappingValue(node, key); current != nil {
+		if current.Kind == yaml.ScalarNode && value.Kind == yaml.ScalarNode {
+			value.Style = current.Style
+		}
+		value.HeadComment, value.LineComment, value.FootComment = current.HeadComment, current.LineComment, current.FootComment
+		*current = *value
+		return
+	}
+
+	// the flow style is used for empty mappings, the new key will be easier to read in block style
+	if len(node.Content) == 0 {
+		node.Style &^= yaml.FlowStyle
+	}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: stringTag, Value: key}, value)
+}
+
+// removeMappingValue removes key from the mapping node, return false if it was not found
+func removeMappingValue(node *yaml.Node, key string) bool {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return false
+	}
+
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		if node.Content[idx].Value == key {
+			node.Content = slices.Delete(node.Content, idx, idx+2)
+			return true
+		}
+	}
+
+	return false
+}
+
+// appendSequenceValue appends value at the end of the sequence node
+func appendSequenceValue(node *yaml.Node, value *yaml.Node) {
+	// the flow style is used for empty sequences, the new value will be easier to read in block style
+	if len(node.Content) == 0 {
+		node.Style &^= yaml.FlowStyle
+	}
+	node.Content = append(node.Content, value)
+}
@@ -0,0 +1,432 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"cmp"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
+)
+
+// openTestEditor return a ConfigEditor for a copy of the testdata file with name
+func openTestEditor(t *testing.T, name string) *ConfigEditor {
+	t.Helper()
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(configPath, data, filePermission))
+
+	editor, err := OpenConfigEditor(configPath)
+	require.NoError(t, err)
+	return editor
+}
+
+func TestConfigEditor(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		configName   string
+		expectedName string
+	}{
+		"v1alpha1 config": {
+			configName:   "editor.yaml",
+			expectedName: "editor-expected.yaml",
+		},
+		"v1alpha2 config": {
+			configName:   "editor-v1alpha2.yaml",
+			expectedName: "editor-v1alpha2-expected.yaml",
+		},
+		"config with its own formatting": {
+			configName:   "editor-formatted.yaml",
+			expectedName: "editor-formatted-expected.yaml",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			testConfigEditor(t, test.configName, test.expectedName)
+		})
+	}
+}
+
+// testConfigEditor applies the same edits to the configName testdata file and checks that the result match expectedName
+func testConfigEditor(t *testing.T, configName, expectedName string) {
+	t.Helper()
+
+	editor := openTestEditor(t, configName)
+
+	production := ConfigScope{Group: "production"}
+	require.NoError(t, editor.SetPackage(ConfigScope{}, "module", "ingress/traefik/base", v1alpha1.Package{Version: "1.21.0"}))
+	require.NoError(t, editor.SetPackage(ConfigScope{}, "addon", "monitoring/traefik", v1alpha1.Package{Version: "1.21.0"}))
+	require.NoError(t, editor.SetPackage(production, "addon", "monitoring/traefik", v1alpha1.Package{
+		Version: "1.22.0",
+		Source:  v1alpha1.Source{URL: "https://example.com/addons.git"},
+	}))
+	require.NoError(t, editor.SetPackage(ConfigScope{Group: "production", Cluster: "cluster-1"}, "module", "ingress/traefik/base", v1alpha1.Package{Version: "1.20"}))
+	require.NoError(t, editor.RemovePackage(ConfigScope{Group: "production", Cluster: "cluster-1"}, "module", "ingress/traefik/base"))
+	require.NoError(t, editor.SetPackage(ConfigScope{Group: "production", Cluster: "cluster-2"}, "module", "ingress/traefik/base", v1alpha1.Package{Disable: true}))
+	require.NoError(t, editor.RemoveCluster("production", "cluster-1"))
+	require.NoError(t, editor.AddCluster("production", "cluster-3", "context-3"))
+	require.NoError(t, editor.AddCluster("staging", "cluster-1", ""))
+	require.NoError(t, editor.RemoveGroup("staging"))
+	require.NoError(t, editor.AddGroup("development"))
+	require.NoError(t, editor.Save())
+
+	expectedData, err := os.ReadFile(filepath.Join("testdata", expectedName))
+	require.NoError(t, err)
+	data, err := os.ReadFile(editor.path)
+	require.NoError(t, err)
+	assert.Equal(t, string(expectedData), string(data))
+
+	config, err := editor.Config()
+	require.NoError(t, err)
+	require.Len(t, config.Spec.Groups, 2)
+	assert.Equal(t, "development", config.Spec.Groups[1].Name)
+	for _, pkg := range config.Spec.Groups[0].AddOns {
+		assert.Equal(t, "monitoring/traefik", pkg.GetName())
+		assert.Equal(t, "1.22.0", pkg.Version)
+	}
+}
+
+func TestConfigEditorUntouchedFile(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{"editor.yaml", "editor-v1alpha2.yaml", "editor-formatted.yaml"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			expectedData, err := os.ReadFile(filepath.Join("testdata", name))
+			require.NoError(t, err)
+
+			editor := openTestEditor(t, name)
+			// setting the version already used doesn't change the file
+			require.NoError(t, editor.SetPackage(ConfigScope{}, "module", "ingress/traefik/base", v1alpha1.Package{Version: "1.20.1"}))
+			data, err := editor.Bytes()
+			require.NoError(t, err)
+			assert.Equal(t, expectedData, data)
+
+			require.NoError(t, editor.Save())
+			data, err = os.ReadFile(editor.path)
+			require.NoError(t, err)
+			assert.Equal(t, expectedData, data)
+		})
+	}
+}
+
+func TestConfigEditorErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		configName    string
+		edit          func(*ConfigEditor) error
+		expectedError string
+	}{
+		"invalid package type": {
+			edit: func(e *ConfigEditor) error {
+				return e.SetPackage(ConfigScope{}, "package", "category/name", v1alpha1.Package{})
+			},
+			expectedError: `invalid package type "package"`,
+		},
+		"module without flavor": {
+			edit: func(e *ConfigEditor) error {
+				return e.SetPackage(ConfigScope{}, "module", "traefik", v1alpha1.Package{})
+			},
+			expectedError: `module "traefik" must include the flavor name`,
+		},
+		"missing group": {
+			edit: func(e *ConfigEditor) error {
+				return e.SetPackage(ConfigScope{Group: "missing"}, "addon", "category/name", v1alpha1.Package{})
+			},
+			expectedError: `group "missing" not found`,
+		},
+		"missing cluster": {
+			edit: func(e *ConfigEditor) error {
+				return e.RemoveCluster("production", "missing")
+			},
+			expectedError: `cluster "missing" not found in group "production"`,
+		},
+		"missing package": {
+			edit: func(e *ConfigEditor) error {
+				return e.RemovePackage(ConfigScope{Group: "production", Cluster: "cluster-2"}, "addon", "category/name")
+			},
+			expectedError: "addon category/name not found in cluster production/cluster-2",
+		},
+		"duplicated group": {
+			edit: func(e *ConfigEditor) error {
+				return e.AddGroup("staging")
+			},
+			expectedError: `group "staging" already exists`,
+		},
+		"duplicated cluster": {
+			edit: func(e *ConfigEditor) error {
+				return e.AddCluster("production", "cluster-1", "context")
+			},
+			expectedError: `cluster "cluster-1" already exists in group "production"`,
+		},
+		"conflicting module flavor": {
+			edit: func(e *ConfigEditor) error {
+				if err := e.SetPackage(ConfigScope{}, "module", "ingress/traefik/ha", v1alpha1.Package{Version: "1.20.1"}); err != nil {
+					return err
+				}
+				_, err := e.Config()
+				return err
+			},
+			expectedError: `module "ingress/traefik" has more than one flavor in spec.modules`,
+		},
+		"different module flavor": {
+			configName: "editor-v1alpha2.yaml",
+			edit: func(e *ConfigEditor) error {
+				return e.RemovePackage(ConfigScope{}, "module", "ingress/traefik/ha")
+			},
+			expectedError: "module ingress/traefik/ha not found in spec",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			editor := openTestEditor(t, cmp.Or(test.configName, "editor.yaml"))
+			assert.ErrorContains(t, test.edit(editor), test.expectedError)
+		})
+	}
+
+	_, err := OpenConfigEditor(filepath.Join("testdata", "missing.yaml"))
+	assert.ErrorContains(t, err, "no such file or directory")
+}
+
+func TestSetPackagesVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		configName   string
+		expectedName string
+	}{
+		"v1alpha1 config": {
+			configName:   "versions.yaml",
+			expectedName: "versions-updated.yaml",
+		},
+		"v1alpha2 config": {
+			configName:   "versions-v1alpha2.yaml",
+			expectedName: "versions-v1alpha2-updated.yaml",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			testSetPackagesVersion(t, test.configName, test.expectedName)
+		})
+	}
+}
+
+// testSetPackagesVersion updates the versions of the configName testdata file and checks that the result match expectedName
+func testSetPackagesVersion(t *testing.T, configName, expectedName string) {
+	t.Helper()
+
+	editor := openTestEditor(t, configName)
+	editor.SetPackagesVersion(map[PackageRef]string{
+		{Group: -1, Cluster: -1, Type: "module", Key: "ingress/traefik/base"}: "1.21",
+		{Group: -1, Cluster: -1, Type: "addon", Key: "monitoring/traefik"}:    "1.21.0",
+		{Group: 0, Cluster: -1, Type: "module", Key: "ingress/traefik/base"}:  "1.21.0",
+		{Group: 0, Cluster: 0, Type: "addon", Key: "monitoring/traefik"}:      "1.22.0",
+		{Group: 0, Cluster: 0, Type: "addon", Key: "missing/addon"}:           "1.22.0",
+	})
+	require.NoError(t, editor.Save())
+
+	expectedData, err := os.ReadFile(filepath.Join("testdata", expectedName))
+	require.NoError(t, err)
+	updatedData, err := os.ReadFile(editor.path)
+	require.NoError(t, err)
+	assert.Equal(t, string(expectedData), string(updatedData))
+
+	config, err := ReadConfig(editor.path)
+	require.NoError(t, err)
+	for _, pkg := range config.Spec.Modules {
+		assert.Equal(t, "1.21", pkg.Version)
+		assert.Equal(t, PackageRef{Group: -1, Cluster: -1, Type: "module", Key: "ingress/traefik/base"}, NewPackageRef(-1, -1, pkg))
+	}
+
+	visited := make([]string, 0)
+	err = WalkPackages(config.Spec, func(ref PackageRef, scope string, pkg v1alpha1.Package) error {
+		visited = append(visited, scope+" "+ref.Key+" "+pkg.Version)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"default ingress/traefik/base 1.21",
+		"default monitoring/traefik 1.21.0",
+		"group ingress/traefik/base ",
+		"group/cluster ingress/traefik/ha 1.20.1",
+		"group/cluster monitoring/traefik 1.22.0",
+	}, visited)
+}
+
+func TestMigrate(t *testing.T) {
+	t.Parallel()
+
+	original, err := ReadConfig(filepath.Join("testdata", "editor.yaml"))
+	require.NoError(t, err)
+
+	editor := openTestEditor(t, "editor.yaml")
+	migrated, err := editor.Migrate()
+	require.NoError(t, err)
+	assert.True(t, migrated)
+	require.NoError(t, editor.Save())
+
+	data, err := os.ReadFile(editor.path)
+	require.NoError(t, err)
+	expectedData, err := os.ReadFile(filepath.Join("testdata", "editor-v1alpha2.yaml"))
+	require.NoError(t, err)
+	// the migrated file keeps the comments of the original one
+	assert.Equal(t, strings.Replace(string(expectedData), " with the v1alpha2 API", "", 1), string(data))
+
+	config, err := ReadConfig(editor.path)
+	require.NoError(t, err)
+	assert.Equal(t, original, config)
+
+	migrated, err = editor.Migrate()
+	require.NoError(t, err)
+	assert.False(t, migrated)
+}
+
+func TestMigrateErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		config        string
+		expectedError string
+	}{
+		"unsupported apiVersion": {
+			config: `kind: ClustersConfiguration
+apiVersion: vab.mia-platform.eu/v1
+name: test
+`,
+			expectedError: `unsupported apiVersion "vab.mia-platform.eu/v1": it must be vab.mia-platform.eu/v1alpha1 or vab.mia-platform.eu/v1alpha2`,
+		},
+		"module without flavor": {
+			config: `kind: ClustersConfiguration
+apiVersion: vab.mia-platform.eu/v1alpha1
+name: test
+spec:
+  modules:
+    ingress/traefik: {}
+`,
+			expectedError: `module "ingress/traefik" must be in the form category/name/flavor to be migrated`,
+		},
+		"module alias": {
+			config: `kind: ClustersConfiguration
+apiVersion: vab.mia-platform.eu/v1alpha1
+name: test
+spec:
+  modules:
+    ingress/traefik/base: &traefik
+      version: 1.20.1
+  groups:
+  - name: group
+    modules:
+      ingress/traefik/base: *traefik
+`,
+			expectedError: `module "ingress/traefik/base" is defined with an alias`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			require.NoError(t, os.WriteFile(configPath, []byte(test.config), filePermission))
+			editor, err := OpenConfigEditor(configPath)
+			require.NoError(t, err)
+
+			_, err = editor.Migrate()
+			assert.ErrorContains(t, err, test.expectedError)
+		})
+	}
+}
+
+func TestConfigEditorIncludedFiles(t *testing.T) {
+	t.Parallel()
+
+	contextPath := t.TempDir()
+	require.NoError(t, os.CopyFS(contextPath, os.DirFS(filepath.Join("testdata", "include"))))
+	configPath := filepath.Join(contextPath, "config.yaml")
+	editor, err := OpenConfigEditor(configPath)
+	require.NoError(t, err)
+
+	development := ConfigScope{Group: "development"}
+	require.NoError(t, editor.SetPackage(development, "module", "ingress/traefik/base", v1alpha1.Package{Version: "1.22.0"}))
+	// the included groups and clusters follow the ones defined in the file including them, like in ReadConfig
+	editor.SetPackagesVersion(map[PackageRef]string{{Group: 1, Cluster: 1, Type: "addon", Key: "monitoring/traefik"}: "1.21.0"})
+	require.NoError(t, editor.AddCluster("development", "cluster-3", "context-4"))
+	assert.ErrorContains(t, editor.AddCluster("development", "cluster-2", "context-4"), `cluster "cluster-2" already exists in group "development"`)
+	assert.ErrorContains(t, editor.RemoveGroup("staging"), `group "staging" is defined in the included file `+filepath.Join(contextPath, "groups", "staging.yaml"))
+	assert.ErrorContains(t, editor.RemoveCluster("development", "cluster-2"), `cluster "cluster-2" of group "development" is defined in the included file `+filepath.Join(contextPath, "groups", "development", "cluster-2.yaml"))
+	require.NoError(t, editor.Save())
+
+	for _, path := range []string{"config.yaml", filepath.Join("groups", "staging.yaml")} {
+		expectedData, err := os.ReadFile(filepath.Join("testdata", "include", path))
+		require.NoError(t, err)
+		data, err := os.ReadFile(filepath.Join(contextPath, path))
+		require.NoError(t, err)
+		assert.Equal(t, string(expectedData), string(data), path)
+	}
+
+	data, err := os.ReadFile(filepath.Join(contextPath, "groups", "development.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, `# the development clusters
+name: development
+modules:
+  ingress/traefik:
+    flavor: base
+    version: 1.22.0
+clusters:
+- name: cluster-1
+  context: context-2
+- name: cluster-3
+  context: context-4
+include:
+- development/*.yaml
+`, string(data))
+
+	data, err = os.ReadFile(filepath.Join(contextPath, "groups", "development", "cluster-2.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, `name: cluster-2
+context: context-3 # the new cluster
+addOns:
+  monitoring/traefik:
+    version: 1.21.0
+`, string(data))
+
+	config, err := editor.Config()
+	require.NoError(t, err)
+	require.Len(t, config.Spec.Groups, 3)
+	clusters := make([]string, 0)
+	for _, cluster := range config.Spec.Groups[1].Clusters {
+		clusters = append(clusters, cluster.Name)
+	}
+	assert.Equal(t, []string{"cluster-1", "cluster-3", "cluster-2"}, clusters)
+}
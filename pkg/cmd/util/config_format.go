@@ -0,0 +1,371 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bytes"
+	"os"
+	"strings"
+
+	yaml "sigs.k8s.io/yaml/goyaml.v3"
+)
+
+// yamlStyle contains the formatting of a yaml file that can be reproduced by the encoder
+type yamlStyle struct {
+	indent           int
+	compactSequences bool
+}
+
+// defaultYAMLStyle is the formatting of all the files written by vab
+var defaultYAMLStyle = yamlStyle{indent: yamlFileIndentation, compactSequences: true}
+
+// yamlFile keeps the content of an edited yaml file as it was read, so its document can be written back
+// changing only the lines of the edited nodes, and keeping the blank lines and the formatting of the others
+type yamlFile struct {
+	data  []byte
+	style yamlStyle
+	// encoded is the document encoded with style when the file was read or written, for finding the edited lines
+	encoded []byte
+}
+
+// newYAMLFile return the yamlFile for data, the content of the file containing document
+func newYAMLFile(data []byte, document *yaml.Node) (*yamlFile, error) {
+	style := detectYAMLStyle(document)
+	encoded, err := style.encode(document)
+	if err != nil {
+		return nil, err
+	}
+
+	return &yamlFile{data: data, style: style, encoded: encoded}, nil
+}
+
+// content return the content of the file with the edits made to document, and document encoded with the style
+// of the file
+func (f *yamlFile) content(document *yaml.Node) ([]byte, []byte, error) {
+	encoded, err := f.style.encode(document)
+	if err != nil {
+		return nil, nil, err
+	}
+	if bytes.Equal(encoded, f.encoded) {
+		return f.data, encoded, nil
+	}
+
+	return patchYAML(f.data, f.encoded, encoded, f.style), encoded, nil
+}
+
+// save writes the edits made to document in the file at path, if there are any
+func (f *yamlFile) save(path string, document *yaml.Node) error {
+	data, encoded, err := f.content(document)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(data, f.data) {
+		return nil
+	}
+
+	if err := os.WriteFile(path, data, filePermission); err != nil {
+		return err
+	}
+	f.data, f.encoded = data, encoded
+	return nil
+}
+
+// encode marshals data with the indentation of the style
+func (s yamlStyle) encode(data interface{}) ([]byte, error) {
+	buffer := new(bytes.Buffer)
+	encoder := yaml.NewEncoder(buffer)
+	encoder.SetIndent(s.indent)
+	if s.compactSequences {
+		encoder.CompactSeqIndent()
+	}
+
+	if err := encoder.Encode(data); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// detectYAMLStyle return the style of the file containing document, looking at the first nested mapping for the
+// indentation and at the first sequence for the indentation of its items, using the default style for what is
+// not found
+func detectYAMLStyle(document *yaml.Node) yamlStyle {
+	style := defaultYAMLStyle
+	indentFound := false
+	sequenceIndent := -1
+	var walk func(node *yaml.Node)
+	walk = func(node *yaml.Node) {
+		if node.Kind == yaml.MappingNode && node.Style&yaml.FlowStyle == 0 {
+			for idx := 0; idx+1 < len(node.Content); idx += 2 {
+				key, value := node.Content[idx], node.Content[idx+1]
+				switch {
+				case value.Style&yaml.FlowStyle != 0 || len(value.Content) == 0 || value.Line <= key.Line:
+				case value.Kind == yaml.MappingNode && !indentFound:
+					if indent := value.Column - key.Column; indent >= 2 && indent <= 9 {
+						style.indent, indentFound = indent, true
+					}
+				case value.Kind == yaml.SequenceNode && sequenceIndent < 0:
+					sequenceIndent = value.Column - key.Column
+				}
+			}
+		}
+		for _, child := range node.Content {
+			walk(child)
+		}
+	}
+	walk(document)
+
+	// the compact sequences have their items indented less than the mappings
+	if sequenceIndent >= 0 {
+		style.compactSequences = sequenceIndent < style.indent
+	}
+	return style
+}
+
+// patchYAML return data, the content of a yaml file whose document is encoded, with the lines changed between
+// encoded and edited replaced. The lines that are not found in data, like the blank ones, are kept where they are.
+// If the changes cannot be applied to data, edited is returned
+func patchYAML(data, encoded, edited []byte, style yamlStyle) []byte {
+	newline := "\n"
+	if bytes.Contains(data, []byte("\r\n")) {
+		newline = "\r\n"
+	}
+	if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+		data = append(bytes.Clone(data), newline...)
+	}
+
+	lines := splitLines(data)
+	encodedLines := splitLines(encoded)
+	editedLines := splitLines(edited)
+	toData := matchLines(encodedLines, lines, normalizeLine)
+	toEdited := matchLines(encodedLines, editedLines, func(line string) string { return line })
+
+	addedLines := reindentLines(editedLines, encodedLines, lines, toEdited, toData)
+	deleted := make([]bool, len(lines))
+	insertBefore := make(map[int][]string)
+	insertAfter := make(map[int][]string)
+	prevEncoded, prevEdited := -1, -1
+	for idx := 0; idx <= len(encodedLines); idx++ {
+		if idx < len(encodedLines) && toEdited[idx] < 0 {
+			continue
+		}
+
+		nextEdited := len(editedLines)
+		if idx < len(encodedLines) {
+			nextEdited = toEdited[idx]
+		}
+
+		// the encoded lines between the two matching ones are removed and the edited ones between them added
+		firstRemoved := -1
+		for removed := prevEncoded + 1; removed < idx; removed++ {
+			if toData[removed] < 0 {
+				return edited
+			}
+			deleted[toData[removed]] = true
+			if firstRemoved < 0 {
+				firstRemoved = toData[removed]
+			}
+		}
+
+		added := make([]string, 0, nextEdited-prevEdited-1)
+		for _, line := range addedLines[prevEdited+1 : nextEdited] {
+			added = append(added, strings.TrimSuffix(line, "\n")+newline)
+		}
+		switch {
+		case len(added) == 0:
+		case firstRemoved >= 0:
+			insertBefore[firstRemoved] = append(insertBefore[firstRemoved], added...)
+		default:
+			anchor := -1
+			for previous := prevEncoded; previous >= 0 && anchor < 0; previous-- {
+				anchor = toData[previous]
+			}
+			insertAfter[anchor] = append(insertAfter[anchor], added...)
+		}
+
+		prevEncoded, prevEdited = idx, nextEdited
+	}
+
+	removeBlankLinesBeforeDeleted(lines, deleted, toData, insertBefore)
+
+	patched := new(bytes.Buffer)
+	writeLines := func(lines []string) {
+		for _, line := range lines {
+			patched.WriteString(line)
+		}
+	}
+	writeLines(insertAfter[-1])
+	for idx, line := range lines {
+		writeLines(insertBefore[idx])
+		if !deleted[idx] {
+			patched.WriteString(line)
+		}
+		writeLines(insertAfter[idx])
+	}
+
+	// the patched file must contain the same document of the edited one
+	document := new(yaml.Node)
+	if err := yaml.Unmarshal(patched.Bytes(), document); err != nil {
+		return edited
+	}
+	if reencoded, err := style.encode(document); err != nil || !bytes.Equal(reencoded, edited) {
+		return edited
+	}
+
+	return patched.Bytes()
+}
+
+// removeBlankLinesBeforeDeleted marks as deleted the blank lines separating a block of deleted lines from the
+// previous ones, if the block is not replaced by other lines and it is at the end of the file or it is followed
+// by other blank lines
+func removeBlankLinesBeforeDeleted(lines []string, deleted []bool, toData []int, insertBefore map[int][]string) {
+	matched := make([]bool, len(lines))
+	for _, idx := range toData {
+		if idx >= 0 {
+			matched[idx] = true
+		}
+	}
+
+	isBlank := func(idx int) bool {
+		return !matched[idx] && len(strings.TrimSpace(lines[idx])) == 0
+	}
+	for end := len(lines) - 1; end >= 0; end-- {
+		if !deleted[end] || (end+1 < len(lines) && (deleted[end+1] || !isBlank(end+1))) {
+			continue
+		}
+
+		start := end
+		for start > 0 && deleted[start-1] {
+			start--
+		}
+		if len(insertBefore[start]) > 0 {
+			end = start
+			continue
+		}
+		for blank := start - 1; blank >= 0 && isBlank(blank); blank-- {
+			deleted[blank] = true
+		}
+		end = start
+	}
+}
+
+// reindentLines return the edited lines with the indentation of the file lines. Every line added to the document
+// is moved like the nearest line preceding it with the same or a lower indentation, and every line matching an
+// encoded one is moved like the matching line of the file
+func reindentLines(editedLines, encodedLines, lines []string, toEdited, toData []int) []string {
+	fromEncoded := make([]int, len(editedLines))
+	for idx := range fromEncoded {
+		fromEncoded[idx] = -1
+	}
+	for idx, edited := range toEdited {
+		if edited >= 0 {
+			fromEncoded[edited] = idx
+		}
+	}
+
+	reindented := make([]string, len(editedLines))
+	shifts := make([]int, len(editedLines))
+	for idx, line := range editedLines {
+		indent := lineIndentation(line)
+		switch encoded := fromEncoded[idx]; {
+		case encoded >= 0 && toData[encoded] >= 0:
+			shifts[idx] = lineIndentation(lines[toData[encoded]]) - lineIndentation(encodedLines[encoded])
+		case encoded < 0:
+			for previous := idx - 1; previous >= 0; previous-- {
+				if lineIndentation(editedLines[previous]) <= indent {
+					shifts[idx] = shifts[previous]
+					break
+				}
+			}
+		}
+
+		reindented[idx] = line
+		if shifts[idx] != 0 && indent+shifts[idx] >= 0 {
+			reindented[idx] = strings.Repeat(" ", indent+shifts[idx]) + line[indent:]
+		}
+	}
+
+	return reindented
+}
+
+// lineIndentation return the number of spaces at the start of line
+func lineIndentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// splitLines return the lines of data including their line terminator
+func splitLines(data []byte) []string {
+	lines := strings.SplitAfter(string(data), "\n")
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// normalizeLine return line without its indentation and line terminator, and with every run of spaces collapsed
+// in a single one, for matching the lines written by the encoder with the ones of a file
+func normalizeLine(line string) string {
+	return strings.Join(strings.Fields(line), " ")
+}
+
+// matchLines return for every line of first the index of the matching line of second, or -1 if it doesn't
+// match, using the longest common subsequence of the lines compared with normalize
+func matchLines(first, second []string, normalize func(string) string) []int {
+	normalizedFirst := make([]string, len(first))
+	for idx, line := range first {
+		normalizedFirst[idx] = normalize(line)
+	}
+	normalizedSecond := make([]string, len(second))
+	for idx, line := range second {
+		normalizedSecond[idx] = normalize(line)
+	}
+
+	// lengths[i][j] is the length of the longest common subsequence of first[i:] and second[j:]
+	lengths := make([][]int, len(first)+1)
+	for idx := range lengths {
+		lengths[idx] = make([]int, len(second)+1)
+	}
+	for i := len(first) - 1; i >= 0; i-- {
+		for j := len(second) - 1; j >= 0; j-- {
+			if normalizedFirst[i] == normalizedSecond[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	matches := make([]int, len(first))
+	i, j := 0, 0
+	for i < len(first) {
+		switch {
+		case j < len(second) && normalizedFirst[i] == normalizedSecond[j]:
+			matches[i] = j
+			i, j = i+1, j+1
+		case j < len(second) && lengths[i][j+1] > lengths[i+1][j]:
+			j++
+		default:
+			matches[i] = -1
+			i++
+		}
+	}
+
+	return matches
+}
@@ -0,0 +1,131 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "sigs.k8s.io/yaml/goyaml.v3"
+)
+
+func TestDetectYAMLStyle(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		data          string
+		expectedStyle yamlStyle
+	}{
+		"default style": {
+			data:          "spec:\n  groups:\n  - name: group\n",
+			expectedStyle: yamlStyle{indent: 2, compactSequences: true},
+		},
+		"indented sequences": {
+			data:          "spec:\n  groups:\n    - name: group\n",
+			expectedStyle: yamlStyle{indent: 2, compactSequences: false},
+		},
+		"four spaces indentation": {
+			data:          "spec:\n    groups:\n      - name: group\n",
+			expectedStyle: yamlStyle{indent: 4, compactSequences: true},
+		},
+		"flow collections": {
+			data:          "spec: {groups: [{name: group}]}\n",
+			expectedStyle: defaultYAMLStyle,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			document := new(yaml.Node)
+			require.NoError(t, yaml.Unmarshal([]byte(test.data), document))
+			assert.Equal(t, test.expectedStyle, detectYAMLStyle(document))
+		})
+	}
+}
+
+func TestPatchYAML(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		data         string
+		edit         func(document *yaml.Node)
+		expectedData string
+	}{
+		"edited value": {
+			data: "# comment\n\nname: test   # the name\n\nversion: 1.0.0\n",
+			edit: func(document *yaml.Node) {
+				setMappingValue(document.Content[0], "version", &yaml.Node{Kind: yaml.ScalarNode, Tag: stringTag, Value: "1.1.0"})
+			},
+			expectedData: "# comment\n\nname: test   # the name\n\nversion: 1.1.0\n",
+		},
+		"added and removed items": {
+			data: "---\ngroups:\n  - name: first\n\n  - name: second\n    clusters: []\n",
+			edit: func(document *yaml.Node) {
+				groups := mappingValue(document.Content[0], "groups")
+				groups.Content = append(groups.Content[:1], &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+					{Kind: yaml.ScalarNode, Tag: stringTag, Value: "name"},
+					{Kind: yaml.ScalarNode, Tag: stringTag, Value: "third"},
+				}})
+			},
+			expectedData: "---\ngroups:\n  - name: first\n\n  - name: third\n",
+		},
+		"windows line endings": {
+			data: "name: test\r\n\r\nversion: 1.0.0\r\n",
+			edit: func(document *yaml.Node) {
+				setMappingValue(document.Content[0], "version", &yaml.Node{Kind: yaml.ScalarNode, Tag: stringTag, Value: "1.1.0"})
+			},
+			expectedData: "name: test\r\n\r\nversion: 1.1.0\r\n",
+		},
+		"indentation not reproduced by the encoder": {
+			data: "groups:\n    - name: group\n      clusters:\n          - name: cluster\n\n",
+			edit: func(document *yaml.Node) {
+				group := mappingValue(document.Content[0], "groups").Content[0]
+				clusters := mappingValue(group, "clusters")
+				clusters.Content = append(clusters.Content, &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+					{Kind: yaml.ScalarNode, Tag: stringTag, Value: "name"},
+					{Kind: yaml.ScalarNode, Tag: stringTag, Value: "new"},
+				}})
+			},
+			expectedData: "groups:\n    - name: group\n      clusters:\n          - name: cluster\n          - name: new\n\n",
+		},
+		"removed line not found in the file": {
+			data: "name:   test\n\nlist: [a,b]\n",
+			edit: func(document *yaml.Node) {
+				document.Content[0].Content = document.Content[0].Content[:2]
+			},
+			expectedData: "name: test\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			document := new(yaml.Node)
+			require.NoError(t, yaml.Unmarshal([]byte(test.data), document))
+			file, err := newYAMLFile([]byte(test.data), document)
+			require.NoError(t, err)
+
+			test.edit(document)
+			data, _, err := file.content(document)
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedData, string(data))
+		})
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	yaml "sigs.k8s.io/yaml/goyaml.v3"
+
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha2"
+)
+
+const (
+	includeKey = "include"
+
+	sequenceTag = "!!seq"
+)
+
+// includedFile is a file included by the configuration, that defines a group or a cluster
+type includedFile struct {
+	path     string
+	document *yaml.Node
+	file     *yamlFile
+}
+
+// configIncludes contains the files included by a configuration: the groups included by the spec and the
+// clusters included by every group, keyed by the node of the group
+type configIncludes struct {
+	groups   []*includedFile
+	clusters map[*yaml.Node][]*includedFile
+}
+
+// root return the mapping defining the group or the cluster in the included file
+func (f *includedFile) root() *yaml.Node {
+	return f.document.Content[0]
+}
+
+// readIncludes reads the files included by the yaml document of the configuration at configPath and by the
+// groups they contain. Only the apiVersions that support it can include other files
+func readIncludes(document *yaml.Node, configPath string) (*configIncludes, error) {
+	includes := &configIncludes{clusters: make(map[*yaml.Node][]*includedFile)}
+	if documentAPIVersion(document) != v1alpha2.Version {
+		return includes, nil
+	}
+
+	spec := mappingValue(document.Content[0], specKey)
+	groupFiles, err := readIncludedFiles(mappingValue(spec, includeKey), filepath.Dir(configPath))
+	if err != nil {
+		return nil, err
+	}
+	includes.groups = groupFiles
+
+	for _, group := range sequenceValues(mappingValue(spec, groupsKey)) {
+		if includes.clusters[group], err = readIncludedFiles(mappingValue(group, includeKey), filepath.Dir(configPath)); err != nil {
+			return nil, err
+		}
+	}
+	for _, file := range groupFiles {
+		if includes.clusters[file.root()], err = readIncludedFiles(mappingValue(file.root(), includeKey), filepath.Dir(file.path)); err != nil {
+			return nil, err
+		}
+	}
+
+	return includes, nil
+}
+
+// readIncludedFiles reads the files matching the paths contained in the include sequence node, that are relative
+// to dir. The files are returned in the order of the paths, and the ones matching a glob pattern or contained in
+// a directory in lexical order
+func readIncludedFiles(include *yaml.Node, dir string) ([]*includedFile, error) {
+	files := make([]*includedFile, 0)
+	seenPaths := make(map[string]bool)
+	for _, pattern := range sequenceValues(include) {
+		path := pattern.Value
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+
+		// a path without glob patterns is read as is, for failing if it doesn't exist
+		matches := []string{path}
+		if strings.ContainsAny(pattern.Value, `*?[\`) {
+			var err error
+			if matches, err = filepath.Glob(path); err != nil {
+				return nil, fmt.Errorf("invalid include pattern %q: %w", pattern.Value, err)
+			}
+		}
+
+		matches, err := expandIncludedDirs(matches)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, match := range matches {
+			if seenPaths[match] {
+				continue
+			}
+			seenPaths[match] = true
+
+			file, err := readIncludedFile(match)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, file)
+		}
+	}
+
+	return files, nil
+}
+
+// expandIncludedDirs replaces the directories contained in paths with the yaml files found inside them, sorted
+// by name. The files of the subdirectories are not included
+func expandIncludedDirs(paths []string) ([]string, error) {
+	expanded := make([]string, 0, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || !info.IsDir() {
+			// missing files are reported when reading them
+			expanded = append(expanded, path)
+			continue
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading included directory: %w", err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			if ext := filepath.Ext(entry.Name()); ext == ".yaml" || ext == ".yml" {
+				expanded = append(expanded, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+
+	return expanded, nil
+}
+
+// readIncludedFile reads the yaml document of the included file at path
+func readIncludedFile(path string) (*includedFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading included file: %w", err)
+	}
+
+	document := new(yaml.Node)
+	if err := yaml.Unmarshal(data, document); err != nil {
+		return nil, fmt.Errorf("reading included file %s: %w", path, err)
+	}
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("reading included file %s: the file doesn't contain a mapping", path)
+	}
+
+	file, err := newYAMLFile(data, document)
+	if err != nil {
+		return nil, fmt.Errorf("reading included file %s: %w", path, err)
+	}
+
+	return &includedFile{path: path, document: document, file: file}, nil
+}
+
+// groupNodes return the groups defined in the document followed by the included ones
+func (i *configIncludes) groupNodes(document *yaml.Node) []*yaml.Node {
+	groups := slices.Clone(sequenceValues(mappingValue(mappingValue(document.Content[0], specKey), groupsKey)))
+	for _, file := range i.groups {
+		groups = append(groups, file.root())
+	}
+
+	return groups
+}
+
+// clusterNodes return the clusters defined in the group followed by the included ones
+func (i *configIncludes) clusterNodes(group *yaml.Node) []*yaml.Node {
+	clusters := slices.Clone(sequenceValues(mappingValue(group, clustersKey)))
+	for _, file := range i.clusters[group] {
+		clusters = append(clusters, file.root())
+	}
+
+	return clusters
+}
+
+// file return the included file defining node, or nil if node is not the root of an included file
+func (i *configIncludes) file(node *yaml.Node) *includedFile {
+	for _, file := range i.files() {
+		if file.root() == node {
+			return file
+		}
+	}
+
+	return nil
+}
+
+// files return all the included files, the ones of every group followed by the ones of its clusters
+func (i *configIncludes) files() []*includedFile {
+	files := slices.Clone(i.groups)
+	for _, clusterFiles := range i.clusters {
+		files = append(files, clusterFiles...)
+	}
+
+	return files
+}
+
+// merge return a copy of the yaml document of the configuration where the included groups and clusters are
+// appended to the ones that include them. The document is not modified, and the nodes are shared with it
+func (i *configIncludes) merge(document *yaml.Node) *yaml.Node {
+	if len(i.files()) == 0 {
+		return document
+	}
+
+	groups := &yaml.Node{Kind: yaml.SequenceNode, Tag: sequenceTag}
+	for _, group := range i.groupNodes(document) {
+		if len(i.clusters[group]) > 0 {
+			clusters := &yaml.Node{Kind: yaml.SequenceNode, Tag: sequenceTag, Content: i.clusterNodes(group)}
+			group = withMappingValue(group, clustersKey, clusters)
+		}
+		groups.Content = append(groups.Content, group)
+	}
+
+	spec := withMappingValue(mappingValue(document.Content[0], specKey), groupsKey, groups)
+	root := withMappingValue(document.Content[0], specKey, spec)
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}
+}
+
+// save writes the included files that have been edited since they were read
+func (i *configIncludes) save() error {
+	for _, file := range i.files() {
+		if err := file.file.save(file.path, file.document); err != nil {
+			return fmt.Errorf("writing included file %s: %w", file.path, err)
+		}
+	}
+
+	return nil
+}
+
+// withMappingValue return a copy of the mapping node where the value of key is value
+func withMappingValue(node *yaml.Node, key string, value *yaml.Node) *yaml.Node {
+	mapping := *node
+	mapping.Content = slices.Clone(node.Content)
+	for idx := 0; idx+1 < len(mapping.Content); idx += 2 {
+		if mapping.Content[idx].Value == key {
+			mapping.Content[idx+1] = value
+			return &mapping
+		}
+	}
+
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: stringTag, Value: key}, value)
+	return &mapping
+}
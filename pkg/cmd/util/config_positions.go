@@ -0,0 +1,90 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Position is the line and column of a node of the configuration file, both starting from 1, and the path of
+// the file included by the configuration that contains it if it isn't the configuration file
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+// IsValid return true if the position points to a node of the configuration file
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String return the position in the line:column form
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// ConfigPositions contains the positions of the nodes of a configuration file addressed by their path, like
+// spec.groups[0].clusters[1].modules.ingress/traefik/base. The position of a dictionary entry is the one of its key
+type ConfigPositions map[string]Position
+
+// Lookup return the position of the node at path, or of its nearest parent found in the configuration file
+// if it is missing. The returned position is not valid if no parent is found
+func (p ConfigPositions) Lookup(path string) Position {
+	for {
+		if position, found := p[path]; found || len(path) == 0 {
+			return position
+		}
+		path = parentPath(path)
+	}
+}
+
+// ScopePath return the path of the group and cluster with the provided indexes, using -1 for the spec
+// or for the group itself
+func ScopePath(group, cluster int) string {
+	path := specKey
+	if group >= 0 {
+		path += "." + groupsKey + "[" + strconv.Itoa(group) + "]"
+	}
+	if group >= 0 && cluster >= 0 {
+		path += "." + clustersKey + "[" + strconv.Itoa(cluster) + "]"
+	}
+
+	return path
+}
+
+// PackagePath return the path of the package referenced by ref, or of the packages dictionary of its type
+// if its Key is empty
+func PackagePath(ref PackageRef) string {
+	path := joinPath(ScopePath(ref.Group, ref.Cluster), packagesKeys[ref.Type])
+	if len(ref.Key) == 0 {
+		return path
+	}
+	return joinPath(path, ref.Key)
+}
+
+// parentPath return the path of the parent of the node at path. The keys containing dots are not supported
+// and are resolved to their parent path
+func parentPath(path string) string {
+	idx := strings.LastIndexAny(path, ".[")
+	if idx < 0 {
+		return ""
+	}
+
+	return path[:idx]
+}
@@ -0,0 +1,108 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigPositions(t *testing.T) {
+	t.Parallel()
+
+	_, positions, err := ReadConfigWithPositions(filepath.Join("testdata", "versions.yaml"))
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		path             string
+		expectedPosition Position
+	}{
+		"root": {
+			path:             "",
+			expectedPosition: Position{Line: 2, Column: 1},
+		},
+		"spec package": {
+			path:             PackagePath(PackageRef{Group: -1, Cluster: -1, Type: "module", Key: "ingress/traefik/base"}),
+			expectedPosition: Position{Line: 8, Column: 5},
+		},
+		"group": {
+			path:             ScopePath(0, -1),
+			expectedPosition: Position{Line: 14, Column: 5},
+		},
+		"cluster package version": {
+			path:             PackagePath(PackageRef{Group: 0, Cluster: 0, Type: "addon", Key: "monitoring/traefik"}) + ".version",
+			expectedPosition: Position{Line: 26, Column: 11},
+		},
+		"missing property use its parent": {
+			path:             PackagePath(PackageRef{Group: 0, Cluster: 0, Type: "module", Key: "ingress/traefik/ha"}) + ".disable",
+			expectedPosition: Position{Line: 22, Column: 9},
+		},
+		"missing cluster use the clusters list": {
+			path:             ScopePath(0, 3) + ".context",
+			expectedPosition: Position{Line: 18, Column: 5},
+		},
+		"missing packages use the scope": {
+			path:             PackagePath(PackageRef{Group: 0, Cluster: -1, Type: "addon"}),
+			expectedPosition: Position{Line: 14, Column: 5},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			position := positions.Lookup(test.path)
+			assert.True(t, position.IsValid())
+			assert.Equal(t, test.expectedPosition, position)
+		})
+	}
+}
+
+func TestConfigPositionsOfV1alpha2Modules(t *testing.T) {
+	t.Parallel()
+
+	_, positions, err := ReadConfigWithPositions(filepath.Join("testdata", "versions-v1alpha2.yaml"))
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		path             string
+		expectedPosition Position
+	}{
+		"spec module": {
+			path:             PackagePath(PackageRef{Group: -1, Cluster: -1, Type: "module", Key: "ingress/traefik/base"}),
+			expectedPosition: Position{Line: 8, Column: 5},
+		},
+		"cluster module version": {
+			path:             PackagePath(PackageRef{Group: 0, Cluster: 0, Type: "module", Key: "ingress/traefik/ha"}) + ".version",
+			expectedPosition: Position{Line: 26, Column: 11},
+		},
+		"module without flavor in key": {
+			path:             PackagePath(PackageRef{Group: 0, Cluster: -1, Type: "module", Key: "ingress/traefik"}) + ".flavor",
+			expectedPosition: Position{Line: 18, Column: 9},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			position := positions.Lookup(test.path)
+			assert.True(t, position.IsValid())
+			assert.Equal(t, test.expectedPosition, position)
+		})
+	}
+}
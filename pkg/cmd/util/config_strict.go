@@ -0,0 +1,304 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"strconv"
+	"strings"
+
+	yaml "sigs.k8s.io/yaml/goyaml.v3"
+
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha2"
+)
+
+const (
+	mergeTag = "!!merge"
+
+	// ReasonUnknownField is the Reason of the ConfigError for a key that is not a field of the configuration
+	ReasonUnknownField = "unknown-field"
+	// ReasonDuplicateKey is the Reason of the ConfigError for a key repeated in the same mapping
+	ReasonDuplicateKey = "duplicate-key"
+	// ReasonConflictingFlavors is the Reason of the ConfigError for a module with more than one flavor in the same scope
+	ReasonConflictingFlavors = "conflicting-flavors"
+)
+
+// ConfigError is a problem found at a position of the configuration file, or of the file included by it
+// when File is set
+type ConfigError struct {
+	File    string
+	Line    int
+	Column  int
+	Reason  string
+	Message string
+}
+
+// Error conform to the error interface
+func (e ConfigError) Error() string {
+	if len(e.File) > 0 {
+		return fmt.Sprintf("%s: line %d, column %d: %s", e.File, e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// ConfigErrors contains all the problems found in the configuration file, ordered by their position
+type ConfigErrors []ConfigError
+
+// Error conform to the error interface
+func (e ConfigErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, configError := range e {
+		messages = append(messages, configError.Error())
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+// configChecker walks the yaml document of a configuration collecting the positions of its nodes, the unknown
+// fields, the duplicated keys and the modules with more than one flavor
+type configChecker struct {
+	errs      ConfigErrors
+	positions ConfigPositions
+
+	// flavorsInKeys is true for the apiVersions that include the flavor in the keys of the modules, otherwise the
+	// positions of the modules are also recorded under the keys including their flavor property
+	flavorsInKeys bool
+	// file is the path of the included file being checked, it is empty for the configuration file
+	file string
+}
+
+// checkConfigDocument return the unknown fields, the duplicated keys and the modules with conflicting flavors found
+// in the yaml document of a configuration and in the files it includes, and the positions of their nodes. The
+// nodes of the included files are addressed by their path once merged in the configuration
+func checkConfigDocument(document *yaml.Node, includes *configIncludes) (ConfigErrors, ConfigPositions) {
+	checker := &configChecker{
+		errs:          make(ConfigErrors, 0),
+		positions:     make(ConfigPositions),
+		flavorsInKeys: documentAPIVersion(document) != v1alpha2.Version,
+	}
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 {
+		return checker.errs, checker.positions
+	}
+
+	configType := reflect.TypeFor[v1alpha1.ClustersConfiguration]()
+	if !checker.flavorsInKeys {
+		configType = reflect.TypeFor[v1alpha2.ClustersConfiguration]()
+	}
+	checker.check(document.Content[0], configType, "")
+
+	inlineGroups := len(sequenceValues(mappingValue(mappingValue(document.Content[0], specKey), groupsKey)))
+	for groupIdx, group := range includes.groupNodes(document) {
+		groupPath := ScopePath(groupIdx, -1)
+		if groupIdx >= inlineGroups {
+			checker.checkIncluded(includes.groups[groupIdx-inlineGroups], reflect.TypeFor[v1alpha2.Group](), groupPath)
+		}
+
+		inlineClusters := len(sequenceValues(mappingValue(group, clustersKey)))
+		for clusterIdx, file := range includes.clusters[group] {
+			checker.checkIncluded(file, reflect.TypeFor[v1alpha2.Cluster](), ScopePath(groupIdx, inlineClusters+clusterIdx))
+		}
+	}
+
+	return checker.errs, checker.positions
+}
+
+// checkIncluded checks the root of the included file as the node of type t at path
+func (c *configChecker) checkIncluded(file *includedFile, t reflect.Type, path string) {
+	c.file = file.path
+	defer func() { c.file = "" }()
+	c.check(file.root(), t, path)
+}
+
+// check records the errors for the keys of the mappings contained in node that are duplicated or that are not
+// fields of the type t it is decoded into. Mismatches between node kinds and types are left to the decoder
+func (c *configChecker) check(node *yaml.Node, t reflect.Type, path string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if _, found := c.positions[path]; !found {
+		c.positions[path] = Position{File: c.file, Line: node.Line, Column: node.Column}
+	}
+
+	switch {
+	case node.Kind == yaml.MappingNode && t.Kind() == reflect.Struct:
+		fields := knownFields(t)
+		c.walkMapping(node, path, func(key, value *yaml.Node) {
+			fieldType, found := fields[key.Value]
+			switch {
+			case !found && key.Value == includeKey && c.flavorsInKeys:
+				c.errs = append(c.errs, c.newError(key, ReasonUnknownField, "unknown field %q%s, including files needs the %s apiVersion", key.Value, pathSuffix(path), v1alpha2.Version))
+				return
+			case !found:
+				c.errs = append(c.errs, c.newError(key, ReasonUnknownField, "unknown field %q%s", key.Value, pathSuffix(path)))
+				return
+			}
+			if key.Value == packagesKeys["module"] && c.flavorsInKeys {
+				c.checkFlavors(value, joinPath(path, key.Value))
+			}
+			c.check(value, fieldType, joinPath(path, key.Value))
+			if key.Value == packagesKeys["module"] && !c.flavorsInKeys {
+				c.aliasFlavors(value, joinPath(path, key.Value))
+			}
+		})
+	case node.Kind == yaml.MappingNode && t.Kind() == reflect.Map:
+		c.walkMapping(node, path, func(key, value *yaml.Node) {
+			c.check(value, t.Elem(), joinPath(path, key.Value))
+		})
+	case node.Kind == yaml.SequenceNode && t.Kind() == reflect.Slice:
+		for idx, value := range node.Content {
+			c.check(value, t.Elem(), path+"["+strconv.Itoa(idx)+"]")
+		}
+	}
+}
+
+// walkMapping call fn for every key and value of the mapping node, after recording the position of the key
+// and reporting it if it is duplicated
+func (c *configChecker) walkMapping(node *yaml.Node, path string, fn func(key, value *yaml.Node)) {
+	seenKeys := make(map[string]*yaml.Node, len(node.Content)/2)
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		key, value := node.Content[idx], node.Content[idx+1]
+		if key.Tag == mergeTag {
+			continue
+		}
+
+		if previous, found := seenKeys[key.Value]; found {
+			c.errs = append(c.errs, c.newError(key, ReasonDuplicateKey, "duplicate key %q%s, already defined at line %d", key.Value, pathSuffix(path), previous.Line))
+			continue
+		}
+		seenKeys[key.Value] = key
+		c.positions[joinPath(path, key.Value)] = Position{File: c.file, Line: key.Line, Column: key.Column}
+		fn(key, value)
+	}
+}
+
+// checkFlavors records an error for every module of the modules mapping node that has the same name of a previous
+// one with a different flavor, because they would be installed together in the same clusters
+func (c *configChecker) checkFlavors(node *yaml.Node, path string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+
+	seenModules := make(map[string]*yaml.Node, len(node.Content)/2)
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		key := node.Content[idx]
+		name, _, found := cutLast(key.Value, "/")
+		if key.Tag == mergeTag || !found {
+			continue
+		}
+
+		previous, found := seenModules[name]
+		switch {
+		case !found:
+			seenModules[name] = key
+		case previous.Value != key.Value:
+			c.errs = append(c.errs, c.newError(key, ReasonConflictingFlavors, "module %q has more than one flavor in %s: %q conflicts with %q defined at line %d",
+				name, path, key.Value, previous.Value, previous.Line))
+		}
+	}
+}
+
+// aliasFlavors records the positions of the modules of the modules mapping node also under the keys including
+// their flavor, that are the ones used for referencing them once converted to v1alpha1
+func (c *configChecker) aliasFlavors(node *yaml.Node, path string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+
+	aliases := make(map[string]Position)
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		key, value := node.Content[idx], node.Content[idx+1]
+		if value.Kind == yaml.AliasNode {
+			value = value.Alias
+		}
+
+		modulePath := joinPath(path, key.Value)
+		for nodePath, position := range c.positions {
+			if nodePath == modulePath || strings.HasPrefix(nodePath, modulePath+".") {
+				aliases[modulePath+"/"+nodeFlavor(value)+strings.TrimPrefix(nodePath, modulePath)] = position
+			}
+		}
+	}
+
+	maps.Copy(c.positions, aliases)
+}
+
+// cutLast slices s around the last instance of sep, returning the text before and after it
+func cutLast(s, sep string) (string, string, bool) {
+	idx := strings.LastIndex(s, sep)
+	if idx < 0 {
+		return s, "", false
+	}
+	return s[:idx], s[idx+len(sep):], true
+}
+
+// knownFields return the yaml keys of the struct type t with the types of their fields, including the
+// ones of the inlined structs
+func knownFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || len(field.Index) > 1 {
+			continue
+		}
+
+		tag := field.Tag.Get("yaml")
+		name, options, _ := strings.Cut(tag, ",")
+		switch {
+		case name == "-":
+			continue
+		case strings.Contains(options, "inline") && field.Type.Kind() == reflect.Struct:
+			for key, fieldType := range knownFields(field.Type) {
+				fields[key] = fieldType
+			}
+			continue
+		case len(name) == 0:
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field.Type
+	}
+
+	return fields
+}
+
+// newError return the ConfigError with reason for node of the file being checked
+func (c *configChecker) newError(node *yaml.Node, reason, format string, args ...any) ConfigError {
+	return ConfigError{File: c.file, Line: node.Line, Column: node.Column, Reason: reason, Message: fmt.Sprintf(format, args...)}
+}
+
+func joinPath(path, key string) string {
+	if len(path) == 0 {
+		return key
+	}
+	return path + "." + key
+}
+
+func pathSuffix(path string) string {
+	if len(path) == 0 {
+		return ""
+	}
+	return " in " + path
+}
@@ -0,0 +1,98 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"encoding/json"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha2"
+)
+
+// jsonSchema contains the parts of a JSON Schema that describe the allowed keys
+type jsonSchema struct {
+	Ref                  string                 `json:"$ref"`
+	Defs                 map[string]*jsonSchema `json:"$defs"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	AdditionalProperties json.RawMessage        `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+}
+
+func TestSchemaMatchesKnownFields(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		schema     []byte
+		configType reflect.Type
+	}{
+		"v1alpha1": {
+			schema:     v1alpha1.ClustersConfigurationSchema,
+			configType: reflect.TypeFor[v1alpha1.ClustersConfiguration](),
+		},
+		"v1alpha2": {
+			schema:     v1alpha2.ClustersConfigurationSchema,
+			configType: reflect.TypeFor[v1alpha2.ClustersConfiguration](),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			schema := new(jsonSchema)
+			require.NoError(t, json.Unmarshal(test.schema, schema))
+			assertSchemaFields(t, schema, schema.Defs, test.configType, "")
+		})
+	}
+}
+
+// assertSchemaFields checks that schema allows the same keys that the strict decoding accepts for the type t at
+// path, and then checks the schemas of its fields, map values and slice items
+func assertSchemaFields(t *testing.T, schema *jsonSchema, defs map[string]*jsonSchema, typ reflect.Type, path string) {
+	t.Helper()
+
+	if len(schema.Ref) > 0 {
+		ref, found := defs[strings.TrimPrefix(schema.Ref, "#/$defs/")]
+		require.True(t, found, "missing definition %s for %s", schema.Ref, path)
+		schema = ref
+	}
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		fields := knownFields(typ)
+		require.Equal(t, slices.Sorted(maps.Keys(fields)), slices.Sorted(maps.Keys(schema.Properties)), "keys of %s", path)
+		for key, fieldType := range fields {
+			assertSchemaFields(t, schema.Properties[key], defs, fieldType, joinPath(path, key))
+		}
+	case reflect.Map:
+		valueSchema := new(jsonSchema)
+		require.NoError(t, json.Unmarshal(schema.AdditionalProperties, valueSchema), "values of %s", path)
+		assertSchemaFields(t, valueSchema, defs, typ.Elem(), path+".*")
+	case reflect.Slice:
+		require.NotNil(t, schema.Items, "items of %s", path)
+		assertSchemaFields(t, schema.Items, defs, typ.Elem(), path+"[*]")
+	}
+}
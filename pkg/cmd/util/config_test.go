@@ -87,11 +87,56 @@ func TestSyncDirectories(t *testing.T) {
 							},
 						},
 					},
+					{
+						Name: "group3",
+						Modules: map[string]v1alpha1.Package{
+							"test/module/base": v1alpha1.NewModule(t, "test/module/base", "v1.29.0", false),
+						},
+						AddOns: map[string]v1alpha1.Package{
+							"test/addon2": v1alpha1.NewAddon(t, "test/addon2", "", true),
+						},
+						Clusters: []v1alpha1.Cluster{
+							{
+								Name: "cluster",
+							},
+							{
+								Name: "cluster2",
+								AddOns: map[string]v1alpha1.Package{
+									"test/addon3": v1alpha1.NewAddon(t, "test/addon3", "v1.0.0", false),
+								},
+							},
+						},
+					},
 				},
 			},
 			path:               t.TempDir(),
 			expectedResultPath: filepath.Join(testdata, "empty"),
 		},
+		"sync project with a cluster overriding only modules": {
+			config: v1alpha1.ConfigSpec{
+				Modules: map[string]v1alpha1.Package{
+					"test/module/base": v1alpha1.NewModule(t, "test/module/base", "v1.28.0", false),
+				},
+				Groups: []v1alpha1.Group{
+					{
+						Name: "group1",
+						Modules: map[string]v1alpha1.Package{
+							"test/module2/flavor": v1alpha1.NewModule(t, "test/module2/flavor", "v1.28.0", false),
+						},
+						Clusters: []v1alpha1.Cluster{
+							{
+								Name: "cluster",
+								Modules: map[string]v1alpha1.Package{
+									"test/module/base": v1alpha1.NewModule(t, "test/module/base", "v1.29.0", false),
+								},
+							},
+						},
+					},
+				},
+			},
+			path:               t.TempDir(),
+			expectedResultPath: filepath.Join(testdata, "modules-only"),
+		},
 		"sync project with old config": {
 			config: v1alpha1.ConfigSpec{
 				Modules: map[string]v1alpha1.Package{
@@ -182,9 +227,16 @@ func TestReadConfig(t *testing.T) {
 				Spec: v1alpha1.ConfigSpec{
 					Modules: make(map[string]v1alpha1.Package),
 					AddOns:  make(map[string]v1alpha1.Package),
+					Source: v1alpha1.Source{
+						URL:       "https://example.com/distribution.git",
+						Path:      "packages",
+						TagScheme: "{type}/{name}/{version}",
+					},
 					Groups: []v1alpha1.Group{
 						{
-							Name: "test-group",
+							Name:    "test-group",
+							Modules: make(map[string]v1alpha1.Package),
+							AddOns:  make(map[string]v1alpha1.Package),
 							Clusters: []v1alpha1.Cluster{
 								{
 									Name:    "test-cluster",
@@ -205,6 +257,32 @@ func TestReadConfig(t *testing.T) {
 			configPath:    filepath.Join(testdata, "invalid.yaml"),
 			expectedError: "could not find expected ':'",
 		},
+		"unknown fields and duplicate keys": {
+			configPath: filepath.Join(testdata, "unknown-fields.yaml"),
+			expectedError: `reading config file: line 7, column 5: unknown field "tag" in spec.source
+line 10, column 7: unknown field "verison" in spec.modules.ingress/traefik/base
+line 11, column 3: unknown field "addons" in spec
+line 16, column 7: duplicate key "env" in spec.groups[0].labels, already defined at line 15
+line 23, column 9: duplicate key "category/addon" in spec.groups[0].clusters[0].addOns, already defined at line 21`,
+		},
+		"modules with more flavors": {
+			configPath: filepath.Join(testdata, "flavors.yaml"),
+			expectedError: `reading config file: line 8, column 5: module "ingress/traefik" has more than one flavor in spec.modules: "ingress/traefik/ha" conflicts with "ingress/traefik/base" defined at line 6
+line 24, column 9: module "cni/cilium" has more than one flavor in spec.groups[0].clusters[0].modules: "cni/cilium/ebpf" conflicts with "cni/cilium/base" defined at line 20`,
+		},
+		"unknown fields in included files": {
+			configPath: filepath.Join(testdata, "include-errors", "config.yaml"),
+			expectedError: `reading config file: testdata/include-errors/groups/group.yaml: line 4, column 5: unknown field "verison" in spec.groups[0].modules.ingress/traefik
+testdata/include-errors/groups/group.yaml: line 7, column 3: unknown field "contxt" in spec.groups[0].clusters[0]`,
+		},
+		"include with v1alpha1": {
+			configPath:    filepath.Join(testdata, "include-v1alpha1.yaml"),
+			expectedError: `reading config file: line 8, column 3: unknown field "include" in spec, including files needs the vab.mia-platform.eu/v1alpha2 apiVersion`,
+		},
+		"missing included file": {
+			configPath:    filepath.Join(testdata, "include-missing.yaml"),
+			expectedError: "reading config file: reading included file: open " + filepath.Join(testdata, "missing.yaml"),
+		},
 		"empty path would use default path": {
 			configPath:    "",
 			expectedError: "open " + defaultConfigFileName,
@@ -227,6 +305,63 @@ func TestReadConfig(t *testing.T) {
 	}
 }
 
+func TestReadV1alpha2Config(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		configPath   string
+		v1alpha1Path string
+	}{
+		"editor config": {
+			configPath:   filepath.Join("testdata", "editor-v1alpha2.yaml"),
+			v1alpha1Path: filepath.Join("testdata", "editor.yaml"),
+		},
+		"versions config": {
+			configPath:   filepath.Join("testdata", "versions-v1alpha2.yaml"),
+			v1alpha1Path: filepath.Join("testdata", "versions.yaml"),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			expectedConfig, err := ReadConfig(test.v1alpha1Path)
+			require.NoError(t, err)
+			config, err := ReadConfig(test.configPath)
+			require.NoError(t, err)
+			assert.Equal(t, expectedConfig, config)
+		})
+	}
+}
+
+func TestReadIncludedFiles(t *testing.T) {
+	t.Parallel()
+
+	expectedConfig, err := ReadConfig(filepath.Join("testdata", "include-merged.yaml"))
+	require.NoError(t, err)
+	config, positions, err := ReadConfigWithPositions(filepath.Join("testdata", "include", "config.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, expectedConfig, config)
+
+	assert.Equal(t, Position{Line: 12, Column: 5}, positions.Lookup(ScopePath(0, -1)))
+	assert.Equal(t, Position{File: filepath.Join("testdata", "include", "groups", "development.yaml"), Line: 2, Column: 1}, positions.Lookup(ScopePath(1, -1)))
+	assert.Equal(t, Position{File: filepath.Join("testdata", "include", "groups", "development", "cluster-2.yaml"), Line: 2, Column: 1}, positions.Lookup(ScopePath(1, 1)+".context"))
+	assert.Equal(t, Position{File: filepath.Join("testdata", "include", "groups", "staging.yaml"), Line: 2, Column: 1}, positions.Lookup(ScopePath(2, -1)+".clusters"))
+}
+
+func TestReadIncludedDirectory(t *testing.T) {
+	t.Parallel()
+
+	config, err := ReadConfig(filepath.Join("testdata", "include-dir", "config.yaml"))
+	require.NoError(t, err)
+	groups := make([]string, 0)
+	for _, group := range config.Spec.Groups {
+		groups = append(groups, group.Name)
+	}
+	assert.Equal(t, []string{"development", "staging"}, groups)
+}
+
 func TestWriteFile(t *testing.T) {
 	t.Parallel()
 
@@ -271,6 +406,24 @@ func TestWriteFile(t *testing.T) {
 	}
 }
 
+func TestMarshalConfig(t *testing.T) {
+	t.Parallel()
+
+	config, err := ReadConfig(filepath.Join("testdata", "versions.yaml"))
+	require.NoError(t, err)
+
+	data, err := encodeYaml(config)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "ingress/traefik/ha:\n")
+	assert.NotContains(t, string(data), "disable: false")
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, data, filePermission))
+	marshaledConfig, err := ReadConfig(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, config, marshaledConfig)
+}
+
 // TODO: copied implementation from new CopyFS function that will land in go 1.23, remove it and use the official one
 // when available
 func copyFS(dir string, fsys fs.FS) error {
@@ -0,0 +1,98 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	yaml "sigs.k8s.io/yaml/goyaml.v3"
+
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
+)
+
+// LockFileName is the name of the file containing the packages lock inside the project folder
+const LockFileName = "vab.lock"
+
+// ReadLock reads the lock file contained in the project at path
+func ReadLock(path string) (*v1alpha1.PackagesLock, error) {
+	lockFile, err := os.ReadFile(filepath.Join(path, LockFileName))
+	if err != nil {
+		return nil, fmt.Errorf("reading lock file: %w", err)
+	}
+
+	output := &v1alpha1.PackagesLock{}
+	if err := yaml.Unmarshal(lockFile, output); err != nil {
+		return nil, fmt.Errorf("reading lock file: %w", err)
+	}
+
+	if output.Kind != v1alpha1.LockKind || output.APIVersion != v1alpha1.Version {
+		return nil, fmt.Errorf("reading lock file: unsupported %s %s", output.APIVersion, output.Kind)
+	}
+
+	return output, nil
+}
+
+// WriteLock writes a lock file containing packages and the resolved ranges in the project at path, the entries
+// are sorted for having a stable output
+func WriteLock(path string, packages []v1alpha1.LockedPackage, ranges []v1alpha1.LockedRange) error {
+	lock := v1alpha1.EmptyLock()
+	lock.Packages = append(lock.Packages, packages...)
+	slices.SortFunc(lock.Packages, func(a, b v1alpha1.LockedPackage) int {
+		return cmp.Or(
+			cmp.Compare(a.Type, b.Type),
+			cmp.Compare(a.Name, b.Name),
+			cmp.Compare(a.Version, b.Version),
+		)
+	})
+
+	if len(ranges) > 0 {
+		lock.Ranges = slices.SortedFunc(slices.Values(ranges), func(a, b v1alpha1.LockedRange) int {
+			return cmp.Or(
+				cmp.Compare(a.Type, b.Type),
+				cmp.Compare(a.Name, b.Name),
+				cmp.Compare(a.Range, b.Range),
+				cmp.Compare(a.Repository, b.Repository),
+			)
+		})
+	}
+
+	if err := writeYamlFile(filepath.Join(path, LockFileName), lock); err != nil {
+		return fmt.Errorf("writing lock file: %w", err)
+	}
+
+	return nil
+}
+
+// ResolveProjectVersionRanges replaces the version ranges of spec with the versions recorded in the lock file of
+// the project at path, or with the highest matching version returned by listVersions if they are not recorded
+func ResolveProjectVersionRanges(spec *v1alpha1.ConfigSpec, path string, listVersions func(v1alpha1.Package) ([]string, error)) error {
+	lock, err := ReadLock(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		lock = v1alpha1.EmptyLock()
+	case err != nil:
+		return err
+	}
+
+	_, err = ResolveVersionRanges(spec, LockedRangeResolver(lock, listVersions))
+	return err
+}
@@ -0,0 +1,94 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
+)
+
+func TestWriteAndReadLock(t *testing.T) {
+	t.Parallel()
+
+	addon := v1alpha1.LockedPackage{
+		Type:       "addon",
+		Name:       "category/addon",
+		Version:    "1.0.0",
+		Repository: "https://example.com/repo.git",
+		Tag:        "addon-category-addon-1.0.0",
+		Commit:     "0123456789abcdef0123456789abcdef01234567",
+		Hash:       "sha256:0000",
+	}
+	module := addon
+	module.Type = "module"
+	module.Name = "category/module"
+	module.Tag = "module-category-module-1.0.0"
+
+	moduleRange := v1alpha1.LockedRange{Type: "module", Name: "category/module", Range: "^1.0.0", Repository: "https://example.com/repo.git", Version: "1.0.0"}
+	addonRange := v1alpha1.LockedRange{Type: "addon", Name: "category/addon", Range: "~1.0.0", Repository: "https://example.com/repo.git", Version: "1.0.0"}
+
+	path := t.TempDir()
+	require.NoError(t, WriteLock(path, []v1alpha1.LockedPackage{module, addon}, []v1alpha1.LockedRange{moduleRange, addonRange}))
+
+	lock, err := ReadLock(path)
+	require.NoError(t, err)
+	assert.Equal(t, v1alpha1.LockKind, lock.Kind)
+	assert.Equal(t, v1alpha1.Version, lock.APIVersion)
+	assert.Equal(t, []v1alpha1.LockedPackage{addon, module}, lock.Packages)
+	assert.Equal(t, []v1alpha1.LockedRange{addonRange, moduleRange}, lock.Ranges)
+}
+
+func TestReadLock(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		data          string
+		expectedError string
+	}{
+		"missing file": {
+			expectedError: "no such file or directory",
+		},
+		"wrong kind": {
+			data:          "kind: ClustersConfiguration\napiVersion: vab.mia-platform.eu/v1alpha1\n",
+			expectedError: "unsupported vab.mia-platform.eu/v1alpha1 ClustersConfiguration",
+		},
+		"invalid yaml": {
+			data:          "packages: {",
+			expectedError: "reading lock file",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			path := t.TempDir()
+			if len(test.data) > 0 {
+				require.NoError(t, os.WriteFile(filepath.Join(path, LockFileName), []byte(test.data), filePermission))
+			}
+
+			lock, err := ReadLock(path)
+			assert.ErrorContains(t, err, test.expectedError)
+			assert.Nil(t, lock)
+		})
+	}
+}
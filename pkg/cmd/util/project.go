@@ -0,0 +1,80 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
+)
+
+// ProjectPaths return the cleaned path of the configuration file set in cf and the absolute path of the
+// project folder containing it
+func ProjectPaths(cf *ConfigFlags) (string, string, error) {
+	configPath := ""
+	if cf.ConfigPath != nil && len(*cf.ConfigPath) > 0 {
+		configPath = filepath.Clean(*cf.ConfigPath)
+	}
+
+	contextPath, err := ValidateContextPath(filepath.Dir(configPath))
+	if err != nil {
+		return "", "", err
+	}
+
+	return configPath, contextPath, nil
+}
+
+// EditProject apply edit to the configuration file at configPath, then save it and update the clusters folders
+// of the project at contextPath. The version ranges are resolved with the lock file of the project, or with the
+// highest matching version returned by listVersions if they are not recorded
+func EditProject(ctx context.Context, configPath, contextPath string, edit func(*ConfigEditor) error, listVersions func(v1alpha1.Package) ([]string, error)) error {
+	logger := logr.FromContextOrDiscard(ctx)
+
+	editor, err := OpenConfigEditor(configPath)
+	if err != nil {
+		return err
+	}
+
+	if err := edit(editor); err != nil {
+		return err
+	}
+
+	config, err := editor.Config()
+	if err != nil {
+		return err
+	}
+
+	// the kustomization files of the clusters need the versions matching the ranges
+	if err := ResolveProjectVersionRanges(&config.Spec, contextPath, listVersions); err != nil {
+		return err
+	}
+
+	logger.V(5).Info("writing config file", "path", configPath)
+	if err := editor.Save(); err != nil {
+		return err
+	}
+
+	logger.V(5).Info("ensuring directories", "path", contextPath)
+	if err := SyncDirectories(config.Spec, contextPath); err != nil {
+		return fmt.Errorf("updating project folders: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
+)
+
+func TestProjectPaths(t *testing.T) {
+	t.Parallel()
+
+	configFlags := NewTestConfigFlags(t, filepath.Join("testdata", "empty.yaml"))
+	configPath, contextPath, err := ProjectPaths(configFlags)
+	require.NoError(t, err)
+	assert.Equal(t, *configFlags.ConfigPath, configPath)
+	assert.Equal(t, filepath.Dir(*configFlags.ConfigPath), contextPath)
+
+	missingPath := filepath.Join(t.TempDir(), "missing", "config.yaml")
+	configFlags.ConfigPath = &missingPath
+	_, _, err = ProjectPaths(configFlags)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestEditProject(t *testing.T) {
+	t.Parallel()
+
+	listVersions := func(v1alpha1.Package) ([]string, error) {
+		return []string{"1.0.0", "1.1.0", "2.0.0"}, nil
+	}
+
+	configFlags := NewTestConfigFlags(t, filepath.Join("testdata", "empty.yaml"))
+	configPath, contextPath, err := ProjectPaths(configFlags)
+	require.NoError(t, err)
+
+	err = EditProject(t.Context(), configPath, contextPath, func(editor *ConfigEditor) error {
+		if err := editor.AddGroup("group"); err != nil {
+			return err
+		}
+		return editor.SetPackage(ConfigScope{}, "module", "test/module/base", v1alpha1.Package{Version: "^1.0.0"})
+	}, listVersions)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "version: ^1.0.0")
+	assert.Contains(t, string(data), "- name: group")
+
+	bases, err := os.ReadFile(filepath.Join(contextPath, "clusters", "all-groups", "bases", "kustomization.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(bases), "test/module-1.1.0/base")
+	assert.DirExists(t, filepath.Join(contextPath, GroupLayerPath("group")))
+
+	expectedData := data
+	editErr := errors.New("edit error")
+	err = EditProject(t.Context(), configPath, contextPath, func(*ConfigEditor) error {
+		return editErr
+	}, listVersions)
+	assert.ErrorIs(t, err, editErr)
+
+	data, err = os.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, string(expectedData), string(data))
+}
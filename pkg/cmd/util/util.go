@@ -21,7 +21,10 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/kustomize/api/krusty"
 	"sigs.k8s.io/kustomize/kyaml/filesys"
 
@@ -72,6 +75,124 @@ func GroupFromConfig(groupName string, path string) (v1alpha1.Group, error) {
 	return group, nil
 }
 
+const (
+	// AllGroupsPattern is the group pattern that matches every group of the configuration
+	AllGroupsPattern = "*"
+
+	minTargetArgs = 2
+	maxTargetArgs = 3
+)
+
+// ParseTargetArgs return the group pattern, the cluster pattern and the context path from the arguments
+// of commands in the form GROUP [CLUSTER] CONTEXT, or CONTEXT alone when all is true
+func ParseTargetArgs(args []string, all bool) (string, string, string, error) {
+	switch {
+	case all && len(args) != 1:
+		return "", "", "", fmt.Errorf("accepts only the CONTEXT argument when targeting all groups, received %d", len(args))
+	case all:
+		return AllGroupsPattern, "", args[0], nil
+	case len(args) < minTargetArgs || len(args) > maxTargetArgs:
+		return "", "", "", fmt.Errorf("accepts GROUP [CLUSTER] CONTEXT arguments, received %d", len(args))
+	case len(args) == maxTargetArgs:
+		return args[0], args[1], args[2], nil
+	default:
+		return args[0], "", args[1], nil
+	}
+}
+
+// TargetCluster is a cluster selected from the configuration together with the name of its group
+type TargetCluster struct {
+	Group   string
+	Cluster v1alpha1.Cluster
+}
+
+// ID return the cluster id of the target
+func (t TargetCluster) ID() string {
+	return ClusterID(t.Group, t.Cluster.Name)
+}
+
+// Path return the path of the target cluster folder relative to the project root
+func (t TargetCluster) Path() string {
+	return ClusterPath(t.Group, t.Cluster.Name)
+}
+
+// ParseSelector return the label selector described by selector, an empty string will select everything
+func ParseSelector(selector string) (labels.Selector, error) {
+	if len(selector) == 0 {
+		return labels.Everything(), nil
+	}
+
+	parsedSelector, err := labels.Parse(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector %q: %w", selector, err)
+	}
+
+	return parsedSelector, nil
+}
+
+// ClustersFromConfig return all the clusters found in the configuration at path whose group matches
+// groupPattern, whose name matches clusterPattern and whose labels match selector. The patterns use the
+// path.Match syntax, an empty clusterPattern will select all the clusters of the matching groups and
+// a nil selector will not filter any cluster.
+func ClustersFromConfig(groupPattern, clusterPattern string, selector labels.Selector, path string) ([]TargetCluster, error) {
+	config, err := ReadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return SelectClusters(config.Spec.Groups, groupPattern, clusterPattern, selector, path)
+}
+
+// SelectClusters return the clusters in groups matching groupPattern, clusterPattern and selector, path
+// is only used for reporting errors
+func SelectClusters(groups []v1alpha1.Group, groupPattern, clusterPattern string, selector labels.Selector, path string) ([]TargetCluster, error) {
+	if selector == nil {
+		selector = labels.Everything()
+	}
+
+	for _, pattern := range []string{groupPattern, clusterPattern} {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	groupFound := false
+	clusterFound := false
+	targets := make([]TargetCluster, 0)
+	for _, group := range groups {
+		// errors are already checked above
+		if match, _ := filepath.Match(groupPattern, group.Name); !match {
+			continue
+		}
+
+		groupFound = true
+		for _, cluster := range group.Clusters {
+			if match, _ := filepath.Match(clusterPattern, cluster.Name); len(clusterPattern) > 0 && !match {
+				continue
+			}
+
+			clusterFound = true
+			if !selector.Matches(labels.Set(group.ClusterLabels(cluster))) {
+				continue
+			}
+			targets = append(targets, TargetCluster{Group: group.Name, Cluster: cluster})
+		}
+	}
+
+	switch {
+	case !groupFound:
+		return nil, fmt.Errorf("no %q group in config at path %q", groupPattern, path)
+	case !clusterFound && len(clusterPattern) == 0:
+		return nil, fmt.Errorf("group %q doesn't have any cluster", groupPattern)
+	case !clusterFound:
+		return nil, fmt.Errorf("group %q doesn't have cluster %q", groupPattern, clusterPattern)
+	case len(targets) == 0:
+		return nil, fmt.Errorf("no cluster matches selector %q", selector.String())
+	}
+
+	return targets, nil
+}
+
 // ValidateContextPath will validate contextPath that is a valid existing path, and that is a directory
 // it will also return the path in absolute form
 func ValidateContextPath(contextPath string) (string, error) {
@@ -92,6 +213,54 @@ func ValidateContextPath(contextPath string) (string, error) {
 	return cleanedContextPath, nil
 }
 
+// ValidatePackageKey return an error if key cannot be used for a package of pkgType in the configuration file,
+// the modules are referenced by category/name/flavor and the add-ons by category/name
+func ValidatePackageKey(pkgType, key string) error {
+	segments := strings.Split(key, "/")
+	switch {
+	case pkgType == "module" && len(segments) != 3:
+		return fmt.Errorf("invalid module %q: it must be in the form category/name/flavor", key)
+	case pkgType == "addon" && len(segments) != 2:
+		return fmt.Errorf("invalid addon %q: it must be in the form category/name", key)
+	case pkgType != "module" && pkgType != "addon":
+		return fmt.Errorf("invalid package type %q", pkgType)
+	}
+
+	for _, segment := range segments {
+		if msgs := validation.IsDNS1123Label(segment); len(msgs) > 0 {
+			return fmt.Errorf("invalid %s %q: %s", pkgType, key, strings.Join(msgs, ", "))
+		}
+	}
+
+	return nil
+}
+
+// ValidateGroupName return an error if name cannot be used for a group, because it is not valid as folder name
+// or it is reserved for the layer shared by all the groups
+func ValidateGroupName(name string) error {
+	if msgs := validation.IsDNS1123Label(name); len(msgs) > 0 {
+		return fmt.Errorf("invalid group name %q: %s", name, strings.Join(msgs, ", "))
+	}
+	if name == AllGroupsDirName {
+		return fmt.Errorf("group name %q is reserved for the layer shared by all the groups", name)
+	}
+
+	return nil
+}
+
+// ValidateClusterName return an error if name cannot be used for a cluster, because it is not valid as folder name
+// or it is reserved for the layer shared by all the clusters of a group
+func ValidateClusterName(name string) error {
+	if msgs := validation.IsDNS1123Label(name); len(msgs) > 0 {
+		return fmt.Errorf("invalid cluster name %q: %s", name, strings.Join(msgs, ", "))
+	}
+	if name == AllClustersDirName {
+		return fmt.Errorf("cluster name %q is reserved for the layer shared by all the clusters of the group", name)
+	}
+
+	return nil
+}
+
 // ClusterID return a cluster identifier for group and cluster name
 func ClusterID(group, cluster string) string {
 	return fmt.Sprintf("%s/%s", group, cluster)
@@ -102,6 +271,11 @@ func ClusterPath(group, cluster string) string {
 	return filepath.Join(clustersDirName, group, cluster)
 }
 
+// GroupLayerPath return the canonical path for the layer shared by all the clusters of group
+func GroupLayerPath(group string) string {
+	return ClusterPath(group, AllClustersDirName)
+}
+
 // VendoredModulePath return a vendored path for module with packageName
 func VendoredModulePath(packageName string) string {
 	return filepath.Join(modulesDirPath, packageName)
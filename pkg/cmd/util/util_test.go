@@ -23,6 +23,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/labels"
 
 	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
 )
@@ -95,7 +96,9 @@ func TestGroupFromConfig(t *testing.T) {
 			path:  configPath,
 			group: "test-group",
 			expectedGroup: v1alpha1.Group{
-				Name: "test-group",
+				Name:    "test-group",
+				Modules: make(map[string]v1alpha1.Package),
+				AddOns:  make(map[string]v1alpha1.Package),
 				Clusters: []v1alpha1.Cluster{
 					{
 						Name:    "test-cluster",
@@ -122,6 +125,187 @@ func TestGroupFromConfig(t *testing.T) {
 	}
 }
 
+func TestParseTargetArgs(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		args            []string
+		all             bool
+		expectedGroup   string
+		expectedCluster string
+		expectedContext string
+		expectedError   string
+	}{
+		"group and context": {
+			args:            []string{"group", "context"},
+			expectedGroup:   "group",
+			expectedContext: "context",
+		},
+		"group, cluster and context": {
+			args:            []string{"group", "cluster", "context"},
+			expectedGroup:   "group",
+			expectedCluster: "cluster",
+			expectedContext: "context",
+		},
+		"all groups": {
+			args:            []string{"context"},
+			all:             true,
+			expectedGroup:   AllGroupsPattern,
+			expectedContext: "context",
+		},
+		"all groups with group argument": {
+			args:          []string{"group", "context"},
+			all:           true,
+			expectedError: "accepts only the CONTEXT argument when targeting all groups, received 2",
+		},
+		"missing group argument": {
+			args:          []string{"context"},
+			expectedError: "accepts GROUP [CLUSTER] CONTEXT arguments, received 1",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			group, cluster, context, err := ParseTargetArgs(test.args, test.all)
+			if len(test.expectedError) > 0 {
+				assert.EqualError(t, err, test.expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedGroup, group)
+			assert.Equal(t, test.expectedCluster, cluster)
+			assert.Equal(t, test.expectedContext, context)
+		})
+	}
+}
+
+func TestSelectClusters(t *testing.T) {
+	t.Parallel()
+
+	groups := []v1alpha1.Group{
+		{
+			Name:   "prod-eu",
+			Labels: map[string]string{"env": "prod", "region": "eu"},
+			Clusters: []v1alpha1.Cluster{
+				{Name: "cluster-1"},
+				{Name: "cluster-2", Labels: map[string]string{"region": "us"}},
+			},
+		},
+		{
+			Name:   "prod-us",
+			Labels: map[string]string{"env": "prod", "region": "us"},
+			Clusters: []v1alpha1.Cluster{
+				{Name: "cluster-1"},
+			},
+		},
+		{
+			Name: "staging",
+			Clusters: []v1alpha1.Cluster{
+				{Name: "cluster-1"},
+			},
+		},
+		{
+			Name: "empty",
+		},
+	}
+
+	tests := map[string]struct {
+		groupPattern   string
+		clusterPattern string
+		selector       string
+		expectedIDs    []string
+		expectedError  string
+	}{
+		"single group": {
+			groupPattern: "prod-eu",
+			expectedIDs:  []string{"prod-eu/cluster-1", "prod-eu/cluster-2"},
+		},
+		"single cluster": {
+			groupPattern:   "prod-eu",
+			clusterPattern: "cluster-2",
+			expectedIDs:    []string{"prod-eu/cluster-2"},
+		},
+		"group pattern": {
+			groupPattern:   "prod-*",
+			clusterPattern: "cluster-1",
+			expectedIDs:    []string{"prod-eu/cluster-1", "prod-us/cluster-1"},
+		},
+		"all groups": {
+			groupPattern: AllGroupsPattern,
+			expectedIDs:  []string{"prod-eu/cluster-1", "prod-eu/cluster-2", "prod-us/cluster-1", "staging/cluster-1"},
+		},
+		"selector across groups": {
+			groupPattern: AllGroupsPattern,
+			selector:     "env=prod,region!=eu",
+			expectedIDs:  []string{"prod-eu/cluster-2", "prod-us/cluster-1"},
+		},
+		"selector without matching clusters": {
+			groupPattern:  AllGroupsPattern,
+			selector:      "env=dev",
+			expectedError: `no cluster matches selector "env=dev"`,
+		},
+		"no matching group": {
+			groupPattern:  "dev-*",
+			expectedError: `no "dev-*" group in config at path "config.yaml"`,
+		},
+		"group without clusters": {
+			groupPattern:  "empty",
+			expectedError: `group "empty" doesn't have any cluster`,
+		},
+		"no matching cluster": {
+			groupPattern:   "prod-*",
+			clusterPattern: "cluster-3",
+			expectedError:  `group "prod-*" doesn't have cluster "cluster-3"`,
+		},
+		"invalid pattern": {
+			groupPattern:  "prod-[",
+			expectedError: `invalid pattern "prod-["`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			selector, err := ParseSelector(test.selector)
+			require.NoError(t, err)
+
+			targets, err := SelectClusters(groups, test.groupPattern, test.clusterPattern, selector, "config.yaml")
+			if len(test.expectedError) > 0 {
+				assert.ErrorContains(t, err, test.expectedError)
+				assert.Nil(t, targets)
+				return
+			}
+
+			assert.NoError(t, err)
+			ids := make([]string, 0, len(targets))
+			for _, target := range targets {
+				ids = append(ids, target.ID())
+			}
+			assert.Equal(t, test.expectedIDs, ids)
+		})
+	}
+}
+
+func TestParseSelector(t *testing.T) {
+	t.Parallel()
+
+	selector, err := ParseSelector("")
+	assert.NoError(t, err)
+	assert.True(t, selector.Empty())
+
+	selector, err = ParseSelector("env=prod,region!=eu")
+	assert.NoError(t, err)
+	assert.True(t, selector.Matches(labels.Set{"env": "prod", "region": "us"}))
+	assert.False(t, selector.Matches(labels.Set{"env": "prod", "region": "eu"}))
+
+	_, err = ParseSelector("env==prod=")
+	assert.ErrorContains(t, err, `invalid selector "env==prod="`)
+}
+
 func TestValidateContextPath(t *testing.T) {
 	t.Parallel()
 
@@ -167,3 +351,66 @@ func TestValidateContextPath(t *testing.T) {
 		})
 	}
 }
+
+func TestValidatePackageKey(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		pkgType       string
+		key           string
+		expectedError string
+	}{
+		"valid module": {
+			pkgType: "module",
+			key:     "ingress/traefik/base",
+		},
+		"valid addon": {
+			pkgType: "addon",
+			key:     "monitoring/traefik",
+		},
+		"module without flavor": {
+			pkgType:       "module",
+			key:           "ingress/traefik",
+			expectedError: "it must be in the form category/name/flavor",
+		},
+		"addon with flavor": {
+			pkgType:       "addon",
+			key:           "monitoring/traefik/base",
+			expectedError: "it must be in the form category/name",
+		},
+		"invalid characters": {
+			pkgType:       "addon",
+			key:           "monitoring/Traefik",
+			expectedError: `invalid addon "monitoring/Traefik"`,
+		},
+		"invalid type": {
+			pkgType:       "package",
+			key:           "monitoring/traefik",
+			expectedError: `invalid package type "package"`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidatePackageKey(test.pkgType, test.key)
+			if len(test.expectedError) > 0 {
+				assert.ErrorContains(t, err, test.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestValidateGroupAndClusterName(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, ValidateGroupName("group-1"))
+	assert.ErrorContains(t, ValidateGroupName("all-groups"), "is reserved")
+	assert.ErrorContains(t, ValidateGroupName("Group_1"), `invalid group name "Group_1"`)
+	assert.NoError(t, ValidateClusterName("cluster-1"))
+	assert.ErrorContains(t, ValidateClusterName("all-clusters"), "is reserved")
+	assert.ErrorContains(t, ValidateClusterName(""), `invalid cluster name ""`)
+}
@@ -0,0 +1,329 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/blang/semver/v4"
+
+	"github.com/mia-platform/vab/internal/git"
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
+)
+
+const (
+	caretPrefix = "^"
+	tildePrefix = "~"
+
+	// versionRangeCharacters contains the characters that can only appear in a version range
+	versionRangeCharacters = "^~<>=! |*"
+)
+
+// VersionsCache stores the versions available for the packages, for listing them only once for every package
+// and source. It is not safe for concurrent use
+type VersionsCache struct {
+	listVersions func(v1alpha1.Package) ([]string, error)
+	versions     map[versionsCacheKey][]string
+}
+
+type versionsCacheKey struct {
+	pkgType string
+	name    string
+	source  v1alpha1.Source
+}
+
+// NewVersionsCache return a VersionsCache that use listVersions for retrieving the versions of a package
+func NewVersionsCache(listVersions func(v1alpha1.Package) ([]string, error)) *VersionsCache {
+	return &VersionsCache{
+		listVersions: listVersions,
+		versions:     make(map[versionsCacheKey][]string),
+	}
+}
+
+// Versions return the versions available for pkg
+func (c *VersionsCache) Versions(pkg v1alpha1.Package) ([]string, error) {
+	key := versionsCacheKey{pkgType: pkg.PackageType(), name: pkg.GetName(), source: pkg.Source}
+	if versions, found := c.versions[key]; found {
+		return versions, nil
+	}
+
+	versions, err := c.listVersions(pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	c.versions[key] = versions
+	return versions, nil
+}
+
+// IsVersionRange return true if version is a range of versions instead of a single version
+func IsVersionRange(version string) bool {
+	return strings.ContainsAny(version, versionRangeCharacters) || strings.Contains(version, ".x")
+}
+
+// IsValidVersion return true if version is a semantic version, optionally prefixed with a v
+func IsValidVersion(version string) bool {
+	_, err := semver.Parse(strings.TrimPrefix(version, "v"))
+	return err == nil
+}
+
+// ParseVersionRange return the semver.Range described by versionRange. In addition to the syntax of
+// semver.ParseRange, the caret (^1.2.3 is >=1.2.3 <2.0.0) and tilde (~1.2.3 is >=1.2.3 <1.3.0) syntaxes,
+// also with partial versions like ^0.0 or ~1, and the * and x wildcards matching any version are supported
+func ParseVersionRange(versionRange string) (semver.Range, error) {
+	versionRange = strings.TrimSpace(versionRange)
+	switch {
+	case isWildcard(versionRange):
+		return func(semver.Version) bool { return true }, nil
+	case strings.HasPrefix(versionRange, caretPrefix):
+		lower, parts, err := parsePartialVersion(strings.TrimPrefix(versionRange, caretPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("invalid version range %q: %w", versionRange, err)
+		}
+
+		// the upper bound is the next version that change the first non zero number, or the last
+		// number specified when they are all zeros
+		var upper semver.Version
+		switch {
+		case lower.Major > 0 || parts == 1:
+			upper = semver.Version{Major: lower.Major + 1}
+		case lower.Minor > 0 || parts == 2:
+			upper = semver.Version{Minor: lower.Minor + 1}
+		default:
+			upper = semver.Version{Patch: lower.Patch + 1}
+		}
+		return boundedRange(lower, upper), nil
+	case strings.HasPrefix(versionRange, tildePrefix):
+		lower, parts, err := parsePartialVersion(strings.TrimPrefix(versionRange, tildePrefix))
+		if err != nil {
+			return nil, fmt.Errorf("invalid version range %q: %w", versionRange, err)
+		}
+
+		// only the patch can change, unless the minor is not specified
+		upper := semver.Version{Major: lower.Major, Minor: lower.Minor + 1}
+		if parts == 1 {
+			upper = semver.Version{Major: lower.Major + 1}
+		}
+		return boundedRange(lower, upper), nil
+	default:
+		versionsRange, err := semver.ParseRange(versionRange)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version range %q: %w", versionRange, err)
+		}
+		return versionsRange, nil
+	}
+}
+
+// isWildcard return true if version is one of the wildcards that match any version
+func isWildcard(version string) bool {
+	switch version {
+	case "*", "x", "X":
+		return true
+	default:
+		return false
+	}
+}
+
+// parsePartialVersion parse version, optionally prefixed with a v, where the minor and patch numbers can be
+// missing or replaced by a wildcard. It return the version with the missing numbers set to zero and how many
+// numbers were specified
+func parsePartialVersion(version string) (semver.Version, int, error) {
+	numbers := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	for idx, number := range numbers {
+		if isWildcard(number) {
+			numbers = numbers[:idx]
+			break
+		}
+	}
+
+	switch len(numbers) {
+	case 0:
+		return semver.Version{}, 0, fmt.Errorf("missing major version in %q", version)
+	case 3:
+		parsed, err := semver.Parse(strings.Join(numbers, "."))
+		return parsed, len(numbers), err
+	default:
+		parsed, err := semver.Parse(strings.Join(append(numbers, "0", "0")[:3], "."))
+		return parsed, len(numbers), err
+	}
+}
+
+// boundedRange return a range matching the versions greater or equal to lower and lesser than upper
+func boundedRange(lower, upper semver.Version) semver.Range {
+	return func(version semver.Version) bool {
+		return version.GTE(lower) && version.LT(upper)
+	}
+}
+
+// ResolveVersion return the highest version in versions that matches versionRange. Versions that are not valid
+// semantic versions, optionally prefixed with a v, and pre-release versions are ignored
+func ResolveVersion(versionRange string, versions []string) (string, error) {
+	matchRange, err := ParseVersionRange(versionRange)
+	if err != nil {
+		return "", err
+	}
+
+	resolved := highestVersion(versions, matchRange)
+	if len(resolved) == 0 {
+		return "", fmt.Errorf("no version matches %q", versionRange)
+	}
+
+	return resolved, nil
+}
+
+// ResolveVersionRanges replaces the version ranges of the enabled packages contained in spec with the versions
+// returned by resolve, and return them. resolve is called once for every range of a package in every repository,
+// with the default source of spec set when the package doesn't have its own
+func ResolveVersionRanges(spec *v1alpha1.ConfigSpec, resolve func(pkg v1alpha1.Package) (string, error)) ([]v1alpha1.LockedRange, error) {
+	resolved := make(map[v1alpha1.LockedRange]string)
+	resolvePackages := func(packages map[string]v1alpha1.Package) error {
+		for key, pkg := range packages {
+			if pkg.Disable || !IsVersionRange(pkg.Version) {
+				continue
+			}
+
+			rangePkg := pkg
+			if rangePkg.Source == (v1alpha1.Source{}) {
+				rangePkg.Source = spec.Source
+			}
+
+			rangeKey := lockedRangeKey(rangePkg)
+			version, found := resolved[rangeKey]
+			if !found {
+				var err error
+				if version, err = resolve(rangePkg); err != nil {
+					return fmt.Errorf("resolving version of %s %s: %w", pkg.PackageType(), pkg.GetName(), err)
+				}
+				resolved[rangeKey] = version
+			}
+
+			pkg.Version = version
+			packages[key] = pkg
+		}
+		return nil
+	}
+
+	packagesMaps := []map[string]v1alpha1.Package{spec.Modules, spec.AddOns}
+	for _, group := range spec.Groups {
+		packagesMaps = append(packagesMaps, group.Modules, group.AddOns)
+		for _, cluster := range group.Clusters {
+			packagesMaps = append(packagesMaps, cluster.Modules, cluster.AddOns)
+		}
+	}
+
+	for _, packages := range packagesMaps {
+		if err := resolvePackages(packages); err != nil {
+			return nil, err
+		}
+	}
+
+	ranges := make([]v1alpha1.LockedRange, 0, len(resolved))
+	for lockedRange, version := range resolved {
+		lockedRange.Version = version
+		ranges = append(ranges, lockedRange)
+	}
+	return ranges, nil
+}
+
+// lockedRangeKey return the LockedRange of pkg without the resolved version, for identifying its range in the
+// repository it is downloaded from
+func lockedRangeKey(pkg v1alpha1.Package) v1alpha1.LockedRange {
+	return v1alpha1.LockedRange{
+		Type:       pkg.PackageType(),
+		Name:       pkg.GetName(),
+		Range:      pkg.Version,
+		Repository: git.SourceLocation(pkg),
+	}
+}
+
+// LockedRangeResolver return a resolve function for ResolveVersionRanges that use the versions recorded in lock,
+// and for the ranges not recorded the highest matching version found in the ones returned by listVersions
+func LockedRangeResolver(lock *v1alpha1.PackagesLock, listVersions func(v1alpha1.Package) ([]string, error)) func(v1alpha1.Package) (string, error) {
+	return func(pkg v1alpha1.Package) (string, error) {
+		rangeKey := lockedRangeKey(pkg)
+		for _, locked := range lock.Ranges {
+			version := locked.Version
+			locked.Version = ""
+			if locked == rangeKey {
+				return version, nil
+			}
+		}
+
+		if _, err := ParseVersionRange(pkg.Version); err != nil {
+			return "", err
+		}
+
+		versions, err := listVersions(pkg)
+		if err != nil {
+			return "", err
+		}
+
+		return ResolveVersion(pkg.Version, versions)
+	}
+}
+
+// LatestVersion return the highest version in versions, ignoring the pre-release versions and the ones that are
+// not valid semantic versions
+func LatestVersion(versions []string) (string, error) {
+	latest := highestVersion(versions, func(semver.Version) bool { return true })
+	if len(latest) == 0 {
+		return "", errors.New("no released version found")
+	}
+
+	return latest, nil
+}
+
+// IsOutdated return true if latest is greater than current, or if it doesn't match current when it is a range.
+// If current is not a valid semantic version it is outdated if it differs from latest
+func IsOutdated(current, latest string) bool {
+	latestVersion, err := semver.ParseTolerant(latest)
+	if err != nil {
+		return false
+	}
+
+	if IsVersionRange(current) {
+		matchRange, err := ParseVersionRange(current)
+		return err == nil && !matchRange(latestVersion)
+	}
+
+	currentVersion, err := semver.ParseTolerant(current)
+	if err != nil {
+		return current != latest
+	}
+
+	return latestVersion.GT(currentVersion)
+}
+
+// highestVersion return the highest version in versions that matches matchRange, without its pre-releases
+func highestVersion(versions []string, matchRange semver.Range) string {
+	resolved := ""
+	var resolvedVersion semver.Version
+	for _, version := range versions {
+		parsedVersion, err := semver.ParseTolerant(version)
+		if err != nil || len(parsedVersion.Pre) > 0 || !matchRange(parsedVersion) {
+			continue
+		}
+
+		if len(resolved) == 0 || parsedVersion.GT(resolvedVersion) {
+			resolved = version
+			resolvedVersion = parsedVersion
+		}
+	}
+
+	return resolved
+}
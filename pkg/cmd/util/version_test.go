@@ -0,0 +1,262 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
+)
+
+func TestIsVersionRange(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]bool{
+		"1.0.0":          false,
+		"v1.0.0":         false,
+		"1.0.0-rc.1":     false,
+		"~1.20.0":        true,
+		"^2.1":           true,
+		">=1.0.0 <2.0.0": true,
+		"1.x":            true,
+		"*":              true,
+	}
+
+	for version, expected := range tests {
+		t.Run(version, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, expected, IsVersionRange(version))
+		})
+	}
+}
+
+func TestIsValidVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]bool{
+		"1.0.0":      true,
+		"v1.0.0":     true,
+		"1.0.0-rc.1": true,
+		"1.0":        false,
+		"latest":     false,
+		"01.0.0":     false,
+		"":           false,
+	}
+
+	for version, expected := range tests {
+		t.Run(version, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, expected, IsValidVersion(version))
+		})
+	}
+}
+
+func TestParseVersionRange(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		matching    []string
+		notMatching []string
+	}{
+		"^1.2.3":         {matching: []string{"1.2.3", "1.9.0"}, notMatching: []string{"1.2.2", "2.0.0"}},
+		"^1.2":           {matching: []string{"1.2.0", "1.9.0"}, notMatching: []string{"1.1.9", "2.0.0"}},
+		"^1":             {matching: []string{"1.0.0", "1.9.9"}, notMatching: []string{"0.9.0", "2.0.0"}},
+		"^0.2.3":         {matching: []string{"0.2.3", "0.2.9"}, notMatching: []string{"0.2.2", "0.3.0"}},
+		"^0.0.3":         {matching: []string{"0.0.3"}, notMatching: []string{"0.0.2", "0.0.4"}},
+		"^0.0":           {matching: []string{"0.0.0", "0.0.9"}, notMatching: []string{"0.1.0"}},
+		"^0":             {matching: []string{"0.0.0", "0.9.9"}, notMatching: []string{"1.0.0"}},
+		"^1.x":           {matching: []string{"1.0.0", "1.9.9"}, notMatching: []string{"2.0.0"}},
+		"~1.2.3":         {matching: []string{"1.2.3", "1.2.9"}, notMatching: []string{"1.2.2", "1.3.0"}},
+		"~1.2":           {matching: []string{"1.2.0", "1.2.9"}, notMatching: []string{"1.1.9", "1.3.0"}},
+		"~1":             {matching: []string{"1.0.0", "1.9.9"}, notMatching: []string{"0.9.9", "2.0.0"}},
+		"~v1.2.x":        {matching: []string{"1.2.0", "1.2.9"}, notMatching: []string{"1.3.0"}},
+		"*":              {matching: []string{"0.0.0", "1.2.3"}},
+		"x":              {matching: []string{"0.0.0", "1.2.3"}},
+		"1.2.x":          {matching: []string{"1.2.0", "1.2.9"}, notMatching: []string{"1.3.0"}},
+		">=1.0.0 <2.0.0": {matching: []string{"1.0.0", "1.9.9"}, notMatching: []string{"0.9.9", "2.0.0"}},
+	}
+
+	for versionRange, test := range tests {
+		t.Run(versionRange, func(t *testing.T) {
+			t.Parallel()
+
+			matchRange, err := ParseVersionRange(versionRange)
+			require.NoError(t, err)
+			for _, version := range test.matching {
+				assert.True(t, matchRange(semver.MustParse(version)), "%s must match %s", versionRange, version)
+			}
+			for _, version := range test.notMatching {
+				assert.False(t, matchRange(semver.MustParse(version)), "%s must not match %s", versionRange, version)
+			}
+		})
+	}
+
+	for _, versionRange := range []string{"^a.b", "~", "^1.2.3.4", "~1.b"} {
+		_, err := ParseVersionRange(versionRange)
+		assert.ErrorContains(t, err, fmt.Sprintf("invalid version range %q", versionRange))
+	}
+}
+
+func TestResolveVersion(t *testing.T) {
+	t.Parallel()
+
+	versions := []string{"v0.1.0", "v0.1.2", "v0.2.0", "v1.19.3", "v1.20.0", "v1.20.4", "v1.21.0", "2.1.0", "2.3.1", "v3.0.0-rc.1", "not-a-version"}
+	tests := map[string]struct {
+		versionRange    string
+		expectedVersion string
+		expectedError   string
+	}{
+		"tilde range": {
+			versionRange:    "~1.20.0",
+			expectedVersion: "v1.20.4",
+		},
+		"caret range": {
+			versionRange:    "^2.1",
+			expectedVersion: "2.3.1",
+		},
+		"caret range with zero major": {
+			versionRange:    "^0.1.0",
+			expectedVersion: "v0.1.2",
+		},
+		"comparison range": {
+			versionRange:    ">=1.0.0 <2.0.0",
+			expectedVersion: "v1.21.0",
+		},
+		"any version": {
+			versionRange:    "*",
+			expectedVersion: "2.3.1",
+		},
+		"wildcard range": {
+			versionRange:    "1.19.x",
+			expectedVersion: "v1.19.3",
+		},
+		"pre-release versions are ignored": {
+			versionRange:  ">=3.0.0-rc.0",
+			expectedError: `no version matches ">=3.0.0-rc.0"`,
+		},
+		"invalid range": {
+			versionRange:  "^a.b",
+			expectedError: `invalid version range "^a.b"`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			version, err := ResolveVersion(test.versionRange, versions)
+			if len(test.expectedError) > 0 {
+				assert.ErrorContains(t, err, test.expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedVersion, version)
+		})
+	}
+}
+
+func TestResolveVersionRangesFromDifferentSources(t *testing.T) {
+	t.Parallel()
+
+	defaultAddon := v1alpha1.NewAddon(t, "category/addon", "^1.0.0", false)
+	forkAddon := v1alpha1.NewAddon(t, "category/addon", "^1.0.0", false)
+	forkAddon.Source = v1alpha1.Source{URL: "https://example.com/fork.git", Path: "packages"}
+	spec := v1alpha1.ConfigSpec{
+		AddOns: map[string]v1alpha1.Package{"addon": defaultAddon},
+		Groups: []v1alpha1.Group{
+			{Name: "group", AddOns: map[string]v1alpha1.Package{"addon": forkAddon}},
+		},
+	}
+
+	lock := v1alpha1.EmptyLock()
+	lock.Ranges = []v1alpha1.LockedRange{
+		{Type: "addon", Name: "category/addon", Range: "^1.0.0", Repository: "https://github.com/mia-platform/distribution", Version: "v1.0.0"},
+	}
+	listVersions := func(pkg v1alpha1.Package) ([]string, error) {
+		assert.Equal(t, forkAddon.Source, pkg.Source)
+		return []string{"v1.0.0", "v1.2.0"}, nil
+	}
+
+	ranges, err := ResolveVersionRanges(&spec, LockedRangeResolver(lock, listVersions))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []v1alpha1.LockedRange{
+		{Type: "addon", Name: "category/addon", Range: "^1.0.0", Repository: "https://github.com/mia-platform/distribution", Version: "v1.0.0"},
+		{Type: "addon", Name: "category/addon", Range: "^1.0.0", Repository: "https://example.com/fork.git//packages", Version: "v1.2.0"},
+	}, ranges)
+	assert.Equal(t, "v1.0.0", spec.AddOns["addon"].Version)
+	assert.Equal(t, "v1.2.0", spec.Groups[0].AddOns["addon"].Version)
+}
+
+func TestLatestVersion(t *testing.T) {
+	t.Parallel()
+
+	latest, err := LatestVersion([]string{"v1.0.0", "v1.2.0", "v1.10.0", "v2.0.0-rc.1", "invalid"})
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.10.0", latest)
+
+	_, err = LatestVersion([]string{"v2.0.0-rc.1", "invalid"})
+	assert.ErrorContains(t, err, "no released version found")
+}
+
+func TestIsOutdated(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		current  string
+		latest   string
+		expected bool
+	}{
+		"older version":          {current: "1.20.1", latest: "v1.21.0", expected: true},
+		"same version":           {current: "v1.21.0", latest: "1.21.0", expected: false},
+		"newer version":          {current: "1.22.0", latest: "1.21.0", expected: false},
+		"range matching latest":  {current: "^1.20.0", latest: "1.21.0", expected: false},
+		"range excluding latest": {current: "~1.20.0", latest: "1.21.0", expected: true},
+		"invalid current":        {current: "latest", latest: "1.21.0", expected: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.expected, IsOutdated(test.current, test.latest))
+		})
+	}
+}
+
+func TestVersionsCache(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	cache := NewVersionsCache(func(v1alpha1.Package) ([]string, error) {
+		calls++
+		return []string{"1.0.0"}, nil
+	})
+
+	module := v1alpha1.NewModule(t, "category/module/flavor", "1.0.0", false)
+	otherFlavor := v1alpha1.NewModule(t, "category/module/other-flavor", "1.0.0", false)
+	addon := v1alpha1.NewAddon(t, "category/module", "1.0.0", false)
+	for _, pkg := range []v1alpha1.Package{module, otherFlavor, addon, module} {
+		versions, err := cache.Versions(pkg)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1.0.0"}, versions)
+	}
+	assert.Equal(t, 2, calls)
+}
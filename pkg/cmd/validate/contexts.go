@@ -0,0 +1,132 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validate
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
+	"github.com/mia-platform/vab/pkg/cmd/util"
+)
+
+// onlineTimeout is the maximum time waited for the response of an API server
+const onlineTimeout = 10 * time.Second
+
+// kubeConfigLoader return the kubeconfig used for checking the clusters contexts
+type kubeConfigLoader func() (*clientcmdapi.Config, error)
+
+// defaultKubeConfigLoader return the kubeconfig merged from the files listed in the KUBECONFIG environment
+// variable, or read from ~/.kube/config if it is not set
+func defaultKubeConfigLoader() (*clientcmdapi.Config, error) {
+	return clientcmd.NewDefaultClientConfigLoadingRules().Load()
+}
+
+// contextTarget is a cluster whose context is checked against the kubeconfig
+type contextTarget struct {
+	path      string
+	clusterID string
+	context   string
+}
+
+// checkContexts checks that the context of every cluster matching the selector is defined in the kubeconfig and
+// that is not used by another cluster. In online mode the API server of every context is contacted for reporting
+// its version, running at most o.jobs requests at the same time
+func (o *Options) checkContexts(spec v1alpha1.ConfigSpec) {
+	kubeConfig, err := o.loadKubeConfig()
+	if err != nil {
+		o.report(RuleKubeConfigUnavailable, "", "", "reading kubeconfig: %s", err)
+		return
+	}
+
+	contextPaths := make(map[string]string)
+	targets := make([]contextTarget, 0)
+	for groupIdx, group := range spec.Groups {
+		for clusterIdx, cluster := range group.Clusters {
+			ref := util.PackageRef{Group: groupIdx, Cluster: clusterIdx}
+			if len(cluster.Context) == 0 || !o.matchesSelector(spec, ref) {
+				continue
+			}
+
+			path := util.ScopePath(groupIdx, clusterIdx) + ".context"
+			clusterID := util.ClusterID(group.Name, cluster.Name)
+			if previousPath, found := contextPaths[cluster.Context]; found {
+				o.report(RuleDuplicateClusterContext, path, clusterID, "context %q is already used at %s", cluster.Context, o.previousLocation(previousPath, path))
+				continue
+			}
+			contextPaths[cluster.Context] = path
+
+			if _, found := kubeConfig.Contexts[cluster.Context]; !found {
+				o.report(RuleUnknownClusterContext, path, clusterID, "context %q not found in kubeconfig", cluster.Context)
+				continue
+			}
+			targets = append(targets, contextTarget{path: path, clusterID: clusterID, context: cluster.Context})
+		}
+	}
+
+	if o.online {
+		o.checkServers(kubeConfig, targets)
+	}
+}
+
+// checkServers contacts the API server of every target and reports its version or the connection error
+func (o *Options) checkServers(kubeConfig *clientcmdapi.Config, targets []contextTarget) {
+	versions := make([]string, len(targets))
+	errs := make([]error, len(targets))
+	serversGroup := new(errgroup.Group)
+	serversGroup.SetLimit(max(o.jobs, 1))
+	for idx, target := range targets {
+		serversGroup.Go(func() error {
+			o.logger.V(5).Info("contacting API server", "cluster", target.clusterID, "context", target.context)
+			versions[idx], errs[idx] = serverVersion(kubeConfig, target.context)
+			return nil
+		})
+	}
+	_ = serversGroup.Wait()
+
+	for idx, target := range targets {
+		if errs[idx] != nil {
+			o.report(RuleUnreachableCluster, target.path, target.clusterID, "API server of context %q not reachable: %s", target.context, errs[idx])
+		} else {
+			o.report(RuleClusterVersion, target.path, target.clusterID, "API server of context %q reports version %s", target.context, versions[idx])
+		}
+	}
+}
+
+// serverVersion return the version reported by the API server of context in kubeConfig
+func serverVersion(kubeConfig *clientcmdapi.Config, context string) (string, error) {
+	restConfig, err := clientcmd.NewNonInteractiveClientConfig(*kubeConfig, context, &clientcmd.ConfigOverrides{}, nil).ClientConfig()
+	if err != nil {
+		return "", err
+	}
+	restConfig.Timeout = onlineTimeout
+
+	client, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := client.ServerVersion()
+	if err != nil {
+		return "", fmt.Errorf("requesting server version: %w", err)
+	}
+	return info.GitVersion, nil
+}
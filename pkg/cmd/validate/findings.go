@@ -0,0 +1,144 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validate
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/mia-platform/vab/pkg/cmd/util"
+)
+
+// Severity is the importance of a finding
+type Severity string
+
+const (
+	// SeverityError is used for the findings that make the configuration invalid
+	SeverityError Severity = "error"
+	// SeverityWarning is used for the findings that can be unexpected but don't make the configuration invalid
+	SeverityWarning Severity = "warn"
+	// SeverityInfo is used for the findings that only inform on the configuration content
+	SeverityInfo Severity = "info"
+)
+
+// Rule identifiers of the checks run on the configuration file
+const (
+	RuleUnknownField            = util.ReasonUnknownField
+	RuleDuplicateKey            = util.ReasonDuplicateKey
+	RuleConflictingFlavors      = util.ReasonConflictingFlavors
+	RuleWrongKind               = "wrong-kind"
+	RuleWrongAPIVersion         = "wrong-api-version"
+	RuleNoModules               = "no-modules"
+	RuleNoAddOns                = "no-addons"
+	RuleNoGroups                = "no-groups"
+	RuleNoClusters              = "no-clusters"
+	RuleDisabledPackage         = "disabled-package"
+	RuleMissingVersion          = "missing-version"
+	RuleMissingFlavor           = "missing-flavor"
+	RuleInvalidVersion          = "invalid-version"
+	RuleInvalidVersionRange     = "invalid-version-range"
+	RuleRedundantOverride       = "redundant-override"
+	RuleMissingGroupName        = "missing-group-name"
+	RuleDuplicateGroupName      = "duplicate-group-name"
+	RuleInvalidGroupName        = "invalid-group-name"
+	RuleReservedGroupName       = "reserved-group-name"
+	RuleMissingClusterName      = "missing-cluster-name"
+	RuleDuplicateClusterName    = "duplicate-cluster-name"
+	RuleInvalidClusterName      = "invalid-cluster-name"
+	RuleReservedClusterName     = "reserved-cluster-name"
+	RuleMissingClusterContext   = "missing-cluster-context"
+	RuleInvalidLabelKey         = "invalid-label-key"
+	RuleInvalidLabelValue       = "invalid-label-value"
+	RuleUnknownPackage          = "unknown-package"
+	RuleUnknownFlavor           = "unknown-flavor"
+	RuleUnknownVersion          = "unknown-package-version"
+	RuleCatalogueUnavailable    = "catalogue-unavailable"
+	RuleBuildFailed             = "build-failed"
+	RuleKubeConfigUnavailable   = "kubeconfig-unavailable"
+	RuleUnknownClusterContext   = "unknown-cluster-context"
+	RuleDuplicateClusterContext = "duplicate-cluster-context"
+	RuleUnreachableCluster      = "unreachable-cluster"
+	RuleClusterVersion          = "cluster-version"
+)
+
+// rule contains the severity and the description of the findings of a check
+type rule struct {
+	severity    Severity
+	description string
+}
+
+// rules contains all the checks run on the configuration file addressed by their identifier
+var rules = map[string]rule{
+	RuleUnknownField:            {SeverityError, "The property is not part of the configuration specification"},
+	RuleDuplicateKey:            {SeverityError, "The key is defined more than once in the same object"},
+	RuleConflictingFlavors:      {SeverityError, "The module is defined with more than one flavor in the same scope"},
+	RuleWrongKind:               {SeverityError, "The kind of the configuration is not ClustersConfiguration"},
+	RuleWrongAPIVersion:         {SeverityError, "The apiVersion of the configuration is not supported"},
+	RuleNoModules:               {SeverityWarning, "No module is defined"},
+	RuleNoAddOns:                {SeverityWarning, "No add-on is defined"},
+	RuleNoGroups:                {SeverityWarning, "No cluster group is defined"},
+	RuleNoClusters:              {SeverityWarning, "The cluster group has no cluster"},
+	RuleDisabledPackage:         {SeverityInfo, "The package is disabled"},
+	RuleMissingVersion:          {SeverityError, "The package has no version"},
+	RuleMissingFlavor:           {SeverityError, "The module has no flavor"},
+	RuleInvalidVersion:          {SeverityError, "The version of the package is not a semantic version"},
+	RuleInvalidVersionRange:     {SeverityError, "The version range of the package cannot be parsed"},
+	RuleRedundantOverride:       {SeverityWarning, "The package overrides the inherited one with the same version"},
+	RuleMissingGroupName:        {SeverityError, "The cluster group has no name"},
+	RuleDuplicateGroupName:      {SeverityError, "The name of the cluster group is already used by another group"},
+	RuleInvalidGroupName:        {SeverityError, "The name of the cluster group cannot be used as folder name"},
+	RuleReservedGroupName:       {SeverityError, "The name of the cluster group is reserved for the layer shared by all the groups"},
+	RuleMissingClusterName:      {SeverityError, "The cluster has no name"},
+	RuleDuplicateClusterName:    {SeverityError, "The name of the cluster is already used by another cluster of the group"},
+	RuleInvalidClusterName:      {SeverityError, "The name of the cluster cannot be used as folder name"},
+	RuleReservedClusterName:     {SeverityError, "The cluster name is reserved for the layer shared by all the clusters of the group"},
+	RuleMissingClusterContext:   {SeverityError, "The cluster has no context"},
+	RuleInvalidLabelKey:         {SeverityError, "The label key cannot be used in a selector"},
+	RuleInvalidLabelValue:       {SeverityError, "The label value cannot be used in a selector"},
+	RuleUnknownPackage:          {SeverityError, "The package is not present in its repository at the requested version"},
+	RuleUnknownFlavor:           {SeverityError, "The flavor is not present inside the module at the requested version"},
+	RuleUnknownVersion:          {SeverityError, "The requested version of the package is not tagged in its repository"},
+	RuleCatalogueUnavailable:    {SeverityError, "The repository of the package cannot be read"},
+	RuleBuildFailed:             {SeverityError, "The kustomize build of the cluster folder fails"},
+	RuleKubeConfigUnavailable:   {SeverityError, "The kubeconfig cannot be read"},
+	RuleUnknownClusterContext:   {SeverityError, "The context of the cluster is not defined in the kubeconfig"},
+	RuleDuplicateClusterContext: {SeverityWarning, "The context of the cluster is already used by another cluster"},
+	RuleUnreachableCluster:      {SeverityError, "The API server of the cluster context cannot be reached"},
+	RuleClusterVersion:          {SeverityInfo, "The version reported by the API server of the cluster context"},
+}
+
+// Finding is a problem or an attention point found at a position of the configuration file
+type Finding struct {
+	Severity Severity `json:"severity"`
+	Rule     string   `json:"rule"`
+	Scope    string   `json:"scope,omitempty"`
+	Message  string   `json:"message"`
+	File     string   `json:"file"`
+	Line     int      `json:"line,omitempty"`
+	Column   int      `json:"column,omitempty"`
+}
+
+// sortFindings orders findings by their file and position, keeping the order of the ones with the same position
+func sortFindings(findings []Finding) {
+	slices.SortStableFunc(findings, func(a, b Finding) int {
+		return cmp.Or(cmp.Compare(a.File, b.File), cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column))
+	})
+}
+
+// hasErrors return true if at least one of findings makes the configuration invalid
+func hasErrors(findings []Finding) bool {
+	return slices.ContainsFunc(findings, func(f Finding) bool { return f.Severity == SeverityError })
+}
@@ -0,0 +1,209 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+)
+
+const (
+	outputText  = "text"
+	outputJSON  = "json"
+	outputSARIF = "sarif"
+
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	toolName     = "vab"
+	toolURI      = "https://github.com/mia-platform/vab"
+)
+
+// outputFormats contains the supported output formats
+var outputFormats = []string{outputText, outputJSON, outputSARIF}
+
+// sarifLevels maps the severities to the levels of the SARIF results
+var sarifLevels = map[Severity]string{
+	SeverityError:   "error",
+	SeverityWarning: "warning",
+	SeverityInfo:    "note",
+}
+
+// jsonReport is the document written with the json output format
+type jsonReport struct {
+	Valid    bool      `json:"valid"`
+	Findings []Finding `json:"findings"`
+}
+
+// sarifLog and the following types contain the subset of the SARIF 2.1.0 format used for reporting the findings
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+}
+
+// writeFindings writes findings to writer in format
+func writeFindings(writer io.Writer, format string, findings []Finding) error {
+	switch format {
+	case outputJSON:
+		return writeJSON(writer, findings)
+	case outputSARIF:
+		return writeSARIF(writer, findings)
+	default:
+		return writeText(writer, findings)
+	}
+}
+
+// writeText writes every finding in the file:line:column: severity: [scope] message form, followed by a
+// success message if the configuration is valid
+func writeText(writer io.Writer, findings []Finding) error {
+	for _, f := range findings {
+		location := f.File
+		if f.Line > 0 {
+			location += fmt.Sprintf(":%d:%d", f.Line, f.Column)
+		}
+
+		scope := ""
+		if len(f.Scope) > 0 {
+			scope = "[" + f.Scope + "] "
+		}
+		if _, err := fmt.Fprintf(writer, "%s: %s: %s%s\n", location, f.Severity, scope, f.Message); err != nil {
+			return err
+		}
+	}
+
+	if hasErrors(findings) {
+		return nil
+	}
+
+	_, err := fmt.Fprintln(writer, "The configuration is valid!")
+	return err
+}
+
+// writeJSON writes findings as a json document reporting also the validity of the configuration
+func writeJSON(writer io.Writer, findings []Finding) error {
+	encoder := newJSONEncoder(writer)
+	return encoder.Encode(jsonReport{
+		Valid:    !hasErrors(findings),
+		Findings: append(make([]Finding, 0, len(findings)), findings...),
+	})
+}
+
+// writeSARIF writes findings as a SARIF log containing all the rules of the validate command
+func writeSARIF(writer io.Writer, findings []Finding) error {
+	sarifRules := make([]sarifRule, 0, len(rules))
+	for _, id := range slices.Sorted(maps.Keys(rules)) {
+		sarifRules = append(sarifRules, sarifRule{
+			ID:                   id,
+			ShortDescription:     sarifMessage{Text: rules[id].description},
+			DefaultConfiguration: sarifConfiguration{Level: sarifLevels[rules[id].severity]},
+		})
+	}
+
+	results := make([]sarifResult, 0, len(findings))
+	for _, f := range findings {
+		location := sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(f.File)}}
+		if f.Line > 0 {
+			location.Region = &sarifRegion{StartLine: f.Line, StartColumn: f.Column}
+		}
+
+		message := f.Message
+		if len(f.Scope) > 0 {
+			message = "[" + f.Scope + "] " + message
+		}
+		results = append(results, sarifResult{
+			RuleID:    f.Rule,
+			Level:     sarifLevels[f.Severity],
+			Message:   sarifMessage{Text: message},
+			Locations: []sarifLocation{{PhysicalLocation: location}},
+		})
+	}
+
+	encoder := newJSONEncoder(writer)
+	return encoder.Encode(sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []sarifRun{{
+			Tool:    sarifTool{Driver: sarifDriver{Name: toolName, InformationURI: toolURI, Rules: sarifRules}},
+			Results: results,
+		}},
+	})
+}
+
+// newJSONEncoder return an encoder writing indented json to writer, without escaping the html characters
+func newJSONEncoder(writer io.Writer) *json.Encoder {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder
+}
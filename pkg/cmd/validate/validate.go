@@ -16,18 +16,27 @@
 package validate
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/MakeNowJust/heredoc/v2"
 	"github.com/go-logr/logr"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/sync/errgroup"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
 
+	"github.com/mia-platform/vab/internal/git"
 	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha1"
+	"github.com/mia-platform/vab/pkg/apis/vab.mia-platform.eu/v1alpha2"
 	"github.com/mia-platform/vab/pkg/cmd/util"
 )
 
@@ -37,20 +46,110 @@ const (
 
 	It returns an error if the config file is malformed or includes resources
 	that do not exist in our catalogue.
+
+	Every finding is printed with the position of the related property in
+	the "file:line:column: level: message" form, ordered by position.
+
+	The --selector flag restricts the checks on clusters to the ones matching
+	the labels set on them and on their groups (e.g. "env=prod,region!=eu").
+
+	The --output flag changes the format of the findings to json or sarif,
+	that include the identifier of the rule of every finding for consumption
+	by other tools.
+
+	The --remote flag also checks the catalogue of packages: every enabled module
+	and add-on must be tagged at the requested version in its repository, and
+	the tag must contain the package folder and the flavor folder of the modules.
+	The repositories are stored in the same local cache of the sync command, and
+	with the --offline flag only the cache is used.
+
+	The --build flag also runs the kustomize build of the folder of every
+	cluster, reporting the clusters that fail to build without printing their
+	manifests; the --jobs flag sets how many clusters are built in parallel.
+
+	The --contexts flag also checks that the context of every cluster is defined
+	in the kubeconfig, merged from the files listed in the KUBECONFIG variable,
+	and that no two clusters share the same context. The --online flag implies
+	it and also reports the version of the API server of every context, or the
+	error returned contacting it.
 `
 
+	selectorFlagName      = "selector"
+	selectorFlagShortName = "l"
+	selectorUsage         = "label selector to filter the validated clusters (e.g. env=prod,region!=eu)"
+	outputFlagName        = "output"
+	outputFlagShortName   = "o"
+	outputUsage           = "format of the findings, one of text, json or sarif"
+	remoteFlagName        = "remote"
+	remoteUsage           = "check that the packages exist in their repositories at the requested versions"
+	offlineFlagName       = "offline"
+	offlineUsage          = "check the packages using only the local cache, without contacting the remote repositories"
+	cacheDirFlagName      = "cache-dir"
+	cacheDirUsage         = "path of the local cache for the packages, by default a folder inside the user cache directory"
+	buildFlagName         = "build"
+	buildUsage            = "build the kustomize folder of every cluster and report the failures"
+	jobsDefaultValue      = 4
+	jobsFlagName          = "jobs"
+	jobsShortName         = "j"
+	jobsUsage             = "the number of clusters to build or contact in parallel"
+	contextsFlagName      = "contexts"
+	contextsUsage         = "check that the clusters contexts are defined in the kubeconfig"
+	onlineFlagName        = "online"
+	onlineUsage           = "check the clusters contexts and that their API servers are reachable"
+
 	defaultScope = "default"
+
+	moduleType = "module"
+	addonType  = "addon"
 )
 
 // Flags contains all the flags for the `validate` command. They will be converted to Options
 // that contains all runtime options for the command.
-type Flags struct{}
+type Flags struct {
+	selector string
+	output   string
+	remote   bool
+	offline  bool
+	cacheDir string
+	build    bool
+	jobs     int
+	contexts bool
+	online   bool
+}
+
+// AddFlags set the connection between Flags property to command line flags
+func (f *Flags) AddFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(&f.selector, selectorFlagName, selectorFlagShortName, "", heredoc.Doc(selectorUsage))
+	flags.StringVarP(&f.output, outputFlagName, outputFlagShortName, outputText, heredoc.Doc(outputUsage))
+	flags.BoolVar(&f.remote, remoteFlagName, false, heredoc.Doc(remoteUsage))
+	flags.BoolVar(&f.offline, offlineFlagName, false, heredoc.Doc(offlineUsage))
+	flags.StringVar(&f.cacheDir, cacheDirFlagName, "", heredoc.Doc(cacheDirUsage))
+	flags.BoolVar(&f.build, buildFlagName, false, heredoc.Doc(buildUsage))
+	flags.IntVarP(&f.jobs, jobsFlagName, jobsShortName, jobsDefaultValue, heredoc.Doc(jobsUsage))
+	flags.BoolVar(&f.contexts, contextsFlagName, false, heredoc.Doc(contextsUsage))
+	flags.BoolVar(&f.online, onlineFlagName, false, heredoc.Doc(onlineUsage))
+}
 
 // Options have the data required to perform the validate operation
 type Options struct {
 	configPath string
+	selector   labels.Selector
+	output     string
 	writer     io.Writer
 	logger     logr.Logger
+
+	// filesGetter is used for checking the packages against their repositories, the check is skipped if nil
+	filesGetter *git.FilesGetter
+	// build enables the kustomize build of the clusters folders, running at most jobs builds at the same time
+	build bool
+	jobs  int
+	// loadKubeConfig is used for checking the clusters contexts, the check is skipped if nil. In online
+	// mode their API servers are also contacted
+	loadKubeConfig kubeConfigLoader
+	online         bool
+
+	positions util.ConfigPositions
+	findings  []Finding
 }
 
 // NewCommand return the command for validating the information inserted in the configuration file
@@ -70,6 +169,7 @@ func NewCommand(cf *util.ConfigFlags) *cobra.Command {
 		},
 	}
 
+	flags.AddFlags(cmd.Flags())
 	return cmd
 }
 
@@ -80,167 +180,445 @@ func (f *Flags) ToOptions(cf *util.ConfigFlags, writer io.Writer) (*Options, err
 		configPath = filepath.Clean(*cf.ConfigPath)
 	}
 
-	return &Options{
-		configPath: configPath,
-		writer:     writer,
-	}, nil
+	selector, err := util.ParseSelector(f.selector)
+	if err != nil {
+		return nil, err
+	}
+
+	output := f.output
+	if len(output) == 0 {
+		output = outputText
+	}
+	if !slices.Contains(outputFormats, output) {
+		return nil, fmt.Errorf("invalid output format %q: must be one of %s", output, strings.Join(outputFormats, ", "))
+	}
+
+	if (f.build || f.online) && f.jobs < 1 {
+		return nil, fmt.Errorf("invalid jobs %d: must be greater than zero", f.jobs)
+	}
+
+	if f.offline && !f.remote {
+		return nil, fmt.Errorf("the --%s flag needs the --%s flag", offlineFlagName, remoteFlagName)
+	}
+
+	var filesGetter *git.FilesGetter
+	if f.remote {
+		cacheDir := f.cacheDir
+		if len(cacheDir) == 0 {
+			if cacheDir, err = git.DefaultCachePath(); err != nil {
+				return nil, err
+			}
+		}
+		filesGetter = git.NewCachedFilesGetter(git.NewCache(filepath.Clean(cacheDir)), f.offline)
+	}
+
+	options := &Options{
+		configPath:  configPath,
+		selector:    selector,
+		output:      output,
+		writer:      writer,
+		filesGetter: filesGetter,
+		build:       f.build,
+		jobs:        f.jobs,
+		online:      f.online,
+	}
+
+	if f.contexts || f.online {
+		options.loadKubeConfig = defaultKubeConfigLoader
+	}
+	return options, nil
 }
 
 // Run execute the create command
 func (o *Options) Run(ctx context.Context) error {
 	o.logger = logr.FromContextOrDiscard(ctx)
-	code := 0
-
-	config, err := util.ReadConfig(o.configPath)
-	if err != nil {
+	o.findings = nil
+
+	config, positions, err := util.ReadConfigWithPositions(o.configPath)
+	configErrors := util.ConfigErrors(nil)
+	switch {
+	case errors.As(err, &configErrors):
+		for _, configError := range configErrors {
+			o.addFinding(configError.Reason, util.Position{File: configError.File, Line: configError.Line, Column: configError.Column}, "", configError.Message)
+		}
+	case err != nil:
 		return fmt.Errorf("parsing configuration file: %w", err)
+	default:
+		o.positions = positions
+		o.checkTypeMeta(&config.TypeMeta)
+		o.logger.V(5).Info("checking TypeMeta for config")
+		o.checkPackages(config.Spec.Modules, nil, moduleType, -1, -1, defaultScope)
+		o.logger.V(5).Info("checking configuration modules")
+		o.checkPackages(config.Spec.AddOns, nil, addonType, -1, -1, defaultScope)
+		o.logger.V(5).Info("checking configuration addons")
+		o.checkGroups(config.Spec)
+		o.logger.V(5).Info("checking configuration groups")
+		if o.filesGetter != nil {
+			o.checkCatalogue(config.Spec)
+			o.logger.V(5).Info("checking packages against their repositories")
+		}
+		if o.build {
+			o.checkBuilds(config.Spec)
+			o.logger.V(5).Info("checking clusters build")
+		}
+		if o.loadKubeConfig != nil {
+			o.checkContexts(config.Spec)
+			o.logger.V(5).Info("checking clusters contexts")
+		}
 	}
 
-	feedbackString := o.checkTypeMeta(&config.TypeMeta, &code)
-	o.logger.V(5).Info("checking TypeMeta for config", "code", code)
-	feedbackString += o.checkModules(&config.Spec.Modules, "", &code)
-	o.logger.V(5).Info("checking configuration modules", "code", code)
-	feedbackString += o.checkAddOns(&config.Spec.AddOns, "", &code)
-	o.logger.V(5).Info("checking configuration addons", "code", code)
-	feedbackString += o.checkGroups(&config.Spec.Groups, &code)
-	o.logger.V(5).Info("checking configuration groups", "code", code)
+	sortFindings(o.findings)
+	if err := writeFindings(o.writer, o.output, o.findings); err != nil {
+		return fmt.Errorf("writing findings: %w", err)
+	}
 
-	fmt.Fprint(o.writer, feedbackString)
-	if code > 0 {
+	if hasErrors(o.findings) {
 		return errors.New("configuration is invalid")
 	}
-
-	fmt.Fprintln(o.writer, "The configuration is valid!")
 	return nil
 }
 
+// report adds a finding of rule for the node at path of the configuration file, or for its nearest parent
+// if the node is missing
+func (o *Options) report(ruleID, path, scope, format string, args ...any) {
+	o.addFinding(ruleID, o.positions.Lookup(path), scope, fmt.Sprintf(format, args...))
+}
+
+// addFinding adds a finding of rule at position with message
+func (o *Options) addFinding(ruleID string, position util.Position, scope, message string) {
+	o.findings = append(o.findings, Finding{
+		Severity: rules[ruleID].severity,
+		Rule:     ruleID,
+		Scope:    scope,
+		Message:  message,
+		File:     o.positionFile(position),
+		Line:     position.Line,
+		Column:   position.Column,
+	})
+}
+
+// positionFile return the file containing position, that is the configuration file unless it is an included one
+func (o *Options) positionFile(position util.Position) string {
+	return cmp.Or(position.File, util.ConfigFilePath(o.configPath))
+}
+
+// previousLocation return the line of the node at previousPath for the message of a finding at path,
+// including its file if the two nodes are in different files
+func (o *Options) previousLocation(previousPath, path string) string {
+	previous := o.positions.Lookup(previousPath)
+	if previous.File == o.positions.Lookup(path).File {
+		return fmt.Sprintf("line %d", previous.Line)
+	}
+	return fmt.Sprintf("line %d of %s", previous.Line, o.positionFile(previous))
+}
+
 // checkTypeMeta checks the file's Kind and APIVersion
-func (o *Options) checkTypeMeta(config *v1alpha1.TypeMeta, code *int) string {
-	outString := ""
+func (o *Options) checkTypeMeta(config *v1alpha1.TypeMeta) {
 	if config.Kind != v1alpha1.Kind {
-		outString += fmt.Sprintf("[error] wrong kind: %s - expected: %s\n", config.Kind, v1alpha1.Kind)
-		*code = 1
+		o.report(RuleWrongKind, "kind", "", "wrong kind: %s - expected: %s", config.Kind, v1alpha1.Kind)
 	}
 
 	if config.APIVersion != v1alpha1.Version {
-		outString += fmt.Sprintf("[error] wrong version: %s - expected: %s\n", config.APIVersion, v1alpha1.Version)
-		*code = 1
+		o.report(RuleWrongAPIVersion, "apiVersion", "", "wrong version: %s - expected: %s or %s", config.APIVersion, v1alpha1.Version, v1alpha2.Version)
 	}
-
-	return outString
 }
 
-// checkModules checks the modules listed in the config file
-func (o *Options) checkModules(packages *map[string]v1alpha1.Package, scope string, code *int) string {
-	if scope == "" {
-		scope = defaultScope
+// checkPackages checks the modules or the addons of the spec, group or cluster with the provided indexes, comparing
+// them with the packages of the same type inherited from the spec and the group
+func (o *Options) checkPackages(packages, inherited map[string]v1alpha1.Package, pkgType string, group, cluster int, scope string) {
+	if len(packages) == 0 {
+		path := util.PackagePath(util.PackageRef{Group: group, Cluster: cluster, Type: pkgType})
+		ruleID := RuleNoModules
+		if pkgType == addonType {
+			ruleID = RuleNoAddOns
+		}
+		o.report(ruleID, path, scope, "no %s found: check the config file if this behavior is unexpected", pkgType)
+		return
 	}
 
-	outString := ""
-	if len(*packages) == 0 {
-		outString += fmt.Sprintf("[warn][%s] no module found: check the config file if this behavior is unexpected\n", scope)
-		return outString
+	keys := make(map[util.PackageRef]string, len(packages))
+	for key, pkg := range packages {
+		keys[util.NewPackageRef(group, cluster, pkg)] = key
 	}
 
-	var outStringSb143 strings.Builder
-	for _, pkg := range *packages {
-		// TODO: add check for modules' uniqueness (only one flavor per module is allowed)
-		if pkg.Disable {
-			outStringSb143.WriteString(fmt.Sprintf("[info][%s] disabling %s %s\n", scope, pkg.PackageType(), pkg.GetName()))
-			continue
+	for _, ref := range slices.SortedFunc(maps.Keys(keys), func(a, b util.PackageRef) int { return cmp.Compare(a.Key, b.Key) }) {
+		pkg := packages[keys[ref]]
+		path := util.PackagePath(ref)
+		switch {
+		case pkg.Disable:
+			o.report(RuleDisabledPackage, path+".disable", scope, "disabling %s %s", pkg.PackageType(), pkg.GetName())
+		case pkg.Version == "":
+			o.report(RuleMissingVersion, path, scope, "missing version of %s %s", pkg.PackageType(), pkg.GetName())
+		default:
+			o.checkVersion(pkg, path+".version", scope)
 		}
 
-		if pkg.Version == "" {
-			outStringSb143.WriteString(fmt.Sprintf("[error][%s] missing version of %s %s\n", scope, pkg.PackageType(), pkg.GetName()))
-			*code = 1
+		// a module without flavor is valid only for disabling the inherited one
+		if pkg.PackageType() == moduleType && !pkg.Disable && pkg.GetFlavorName() == "" {
+			o.report(RuleMissingFlavor, path+".flavor", scope, "missing flavor of module %s", pkg.GetName())
 		}
-	}
-	outString += outStringSb143.String()
 
-	return outString
-}
-
-// checkAddOns checks the addons listed in the config file
-func (o *Options) checkAddOns(packages *map[string]v1alpha1.Package, scope string, code *int) string {
-	if scope == "" {
-		scope = defaultScope
-	}
-
-	outString := ""
-	if len(*packages) == 0 {
-		outString += fmt.Sprintf("[warn][%s] no addon found: check the config file if this behavior is unexpected\n", scope)
-		return outString
-	}
-
-	var outStringSb171 strings.Builder
-	for _, pkg := range *packages {
-		if pkg.Disable {
-			outStringSb171.WriteString(fmt.Sprintf("[info][%s] disabling %s %s\n", scope, pkg.PackageType(), pkg.GetName()))
-		} else if pkg.Version == "" {
-			outString += fmt.Sprintf("[error][%s] missing version of %s %s\n", scope, pkg.PackageType(), pkg.GetName())
-			*code = 1
+		if parent, found := inherited[keys[ref]]; found && isRedundantOverride(pkg, parent) {
+			o.report(RuleRedundantOverride, path+".version", scope, "%s %s overrides the inherited one with the same version %s", pkg.PackageType(), pkg.GetName(), pkg.Version)
 		}
 	}
-	outString += outStringSb171.String()
-	return outString
 }
 
-// checkGroups checks the cluster groups listed in the config file
-func (o *Options) checkGroups(groups *[]v1alpha1.Group, code *int) string {
-	outString := ""
+// isRedundantOverride return true if pkg is enabled and installs the same flavor, version and source of parent
+func isRedundantOverride(pkg, parent v1alpha1.Package) bool {
+	return !pkg.Disable && !parent.Disable &&
+		len(pkg.Version) > 0 && pkg.Version == parent.Version &&
+		pkg.GetFlavorName() == parent.GetFlavorName() &&
+		pkg.Source == parent.Source
+}
 
-	if len(*groups) == 0 {
-		outString += "[warn] no group found: check the config file if this behavior is unexpected\n"
-		return outString
+// checkGroups checks the cluster groups listed in the config file
+func (o *Options) checkGroups(spec v1alpha1.ConfigSpec) {
+	if len(spec.Groups) == 0 {
+		o.report(RuleNoGroups, util.ScopePath(0, -1), "", "no group found: check the config file if this behavior is unexpected")
+		return
 	}
 
-	var outStringSb191 strings.Builder
-	for _, g := range *groups {
-		groupName := g.Name
+	namePaths := make(map[string]string, len(spec.Groups))
+	for groupIdx, group := range spec.Groups {
+		groupPath := util.ScopePath(groupIdx, -1)
+		groupName := group.Name
 		if groupName == "" {
-			outStringSb191.WriteString("[error] please specify a valid name for each group\n")
+			o.report(RuleMissingGroupName, groupPath+".name", "", "please specify a valid name for each group")
 			groupName = "undefined"
-			*code = 1
+		} else {
+			o.checkGroupName(groupName, groupPath+".name", namePaths)
+		}
+
+		o.checkLabels(group.Labels, groupPath, groupName)
+		if len(group.Modules) > 0 {
+			o.checkPackages(group.Modules, spec.Modules, moduleType, groupIdx, -1, groupName)
+			o.logger.V(5).Info(fmt.Sprintf("checking group %s modules", groupName))
+		}
+		if len(group.AddOns) > 0 {
+			o.checkPackages(group.AddOns, spec.AddOns, addonType, groupIdx, -1, groupName)
+			o.logger.V(5).Info(fmt.Sprintf("checking group %s addons", groupName))
+		}
+
+		inherited := v1alpha1.Group{
+			Modules: overridePackages(spec.Modules, group.Modules),
+			AddOns:  overridePackages(spec.AddOns, group.AddOns),
 		}
+		o.checkClusters(group, inherited, groupIdx, groupName)
+		o.logger.V(5).Info(fmt.Sprintf("checking group %s clusters", groupName))
+	}
+}
 
-		group := g
-		outStringSb191.WriteString(o.checkClusters(&group, groupName, code))
-		o.logger.V(5).Info(fmt.Sprintf("checking group %s clusters", groupName), "", *code)
+// checkGroupName checks that name is not already used by the groups in namePaths and that can be used as folder
+// name, then records the path of its node in namePaths
+func (o *Options) checkGroupName(name, path string, namePaths map[string]string) {
+	if previousPath, found := namePaths[name]; found {
+		o.report(RuleDuplicateGroupName, path, "", "duplicate group name %q: already used at %s", name, o.previousLocation(previousPath, path))
+	} else {
+		namePaths[name] = path
 	}
-	outString += outStringSb191.String()
 
-	return outString
+	if msgs := validation.IsDNS1123Label(name); len(msgs) > 0 {
+		o.report(RuleInvalidGroupName, path, "", "invalid group name %q: %s", name, strings.Join(msgs, ", "))
+	}
+	if name == util.AllGroupsDirName {
+		o.report(RuleReservedGroupName, path, "", "group name %q is reserved for the layer shared by all the groups", name)
+	}
 }
 
-// checkClusters checks the clusters of a group
-func (o *Options) checkClusters(group *v1alpha1.Group, groupName string, code *int) string {
-	outString := ""
+// checkClusters checks the clusters of a group, the inherited group contains the packages of the spec
+// merged with the ones of group
+func (o *Options) checkClusters(group, inherited v1alpha1.Group, groupIdx int, groupName string) {
 	if len(group.Clusters) == 0 {
-		outString += fmt.Sprintf("[warn][%s] no cluster found in group: check the config file if this behavior is unexpected\n", groupName)
-		return outString
+		o.report(RuleNoClusters, util.ScopePath(groupIdx, -1)+".clusters", groupName, "no cluster found in group: check the config file if this behavior is unexpected")
+		return
 	}
 
-	var outStringSb215 strings.Builder
-	for _, cluster := range group.Clusters {
+	namePaths := make(map[string]string, len(group.Clusters))
+	for clusterIdx, cluster := range group.Clusters {
+		clusterPath := util.ScopePath(groupIdx, clusterIdx)
+		previousPath, duplicated := namePaths[cluster.Name]
+		if !duplicated {
+			namePaths[cluster.Name] = clusterPath + ".name"
+		}
+
+		if o.selector != nil && !o.selector.Matches(labels.Set(group.ClusterLabels(cluster))) {
+			o.logger.V(5).Info("skipping cluster not matching selector", "cluster", util.ClusterID(groupName, cluster.Name))
+			continue
+		}
+
 		clusterName := cluster.Name
-		if clusterName == "" {
-			outStringSb215.WriteString(fmt.Sprintf("[error][%s] missing cluster name in group: please specify a valid name for each cluster\n", groupName))
-			*code = 1
+		switch {
+		case clusterName == "":
+			o.report(RuleMissingClusterName, clusterPath+".name", groupName, "missing cluster name in group: please specify a valid name for each cluster")
 			clusterName = "undefined"
+		case duplicated:
+			o.report(RuleDuplicateClusterName, clusterPath+".name", groupName, "duplicate cluster name %q: already used at %s", clusterName, o.previousLocation(previousPath, clusterPath+".name"))
+		}
+
+		if msgs := validation.IsDNS1123Label(clusterName); len(msgs) > 0 {
+			o.report(RuleInvalidClusterName, clusterPath+".name", groupName, "invalid cluster name %q: %s", clusterName, strings.Join(msgs, ", "))
+		}
+		if clusterName == util.AllClustersDirName {
+			o.report(RuleReservedClusterName, clusterPath+".name", groupName, "cluster name %q is reserved for the layer shared by all the clusters of the group", clusterName)
 		}
 
 		clusterID := util.ClusterID(groupName, clusterName)
 		if cluster.Context == "" {
-			outStringSb215.WriteString(fmt.Sprintf("[error][%s] missing cluster context: please specify a valid context for each cluster\n", clusterID))
-			*code = 1
+			o.report(RuleMissingClusterContext, clusterPath+".context", clusterID, "missing cluster context: please specify a valid context for each cluster")
 		}
 
-		outStringSb215.WriteString(o.checkModules(&cluster.Modules, clusterID, code))
-		o.logger.V(5).Info(fmt.Sprintf("checking cluster %s modules", clusterID), "code", *code)
-		outStringSb215.WriteString(o.checkAddOns(&cluster.AddOns, clusterID, code))
-		o.logger.V(5).Info(fmt.Sprintf("checking cluster %s addon", clusterID), "code", *code)
+		o.checkLabels(cluster.Labels, clusterPath, clusterID)
+		o.checkPackages(cluster.Modules, inherited.Modules, moduleType, groupIdx, clusterIdx, clusterID)
+		o.logger.V(5).Info(fmt.Sprintf("checking cluster %s modules", clusterID))
+		o.checkPackages(cluster.AddOns, inherited.AddOns, addonType, groupIdx, clusterIdx, clusterID)
+		o.logger.V(5).Info(fmt.Sprintf("checking cluster %s addon", clusterID))
+	}
+}
+
+// overridePackages return a new map containing the packages of parent replaced or extended by the ones of child
+func overridePackages(parent, child map[string]v1alpha1.Package) map[string]v1alpha1.Package {
+	packages := make(map[string]v1alpha1.Package, len(parent)+len(child))
+	maps.Copy(packages, parent)
+	maps.Copy(packages, child)
+	return packages
+}
+
+// checkLabels checks that keys and values of labels are valid for being used in a selector
+func (o *Options) checkLabels(labelSet map[string]string, scopePath, scope string) {
+	for _, key := range slices.Sorted(maps.Keys(labelSet)) {
+		path := scopePath + ".labels." + key
+		for _, msg := range validation.IsQualifiedName(key) {
+			o.report(RuleInvalidLabelKey, path, scope, "invalid label key %q: %s", key, msg)
+		}
+		for _, msg := range validation.IsValidLabelValue(labelSet[key]) {
+			o.report(RuleInvalidLabelValue, path, scope, "invalid value for label %q: %s", key, msg)
+		}
 	}
-	outString += outStringSb215.String()
+}
 
-	return outString
+// checkVersion checks that the version of pkg is a semantic version or a valid version range
+func (o *Options) checkVersion(pkg v1alpha1.Package, path, scope string) {
+	if !util.IsVersionRange(pkg.Version) {
+		if !util.IsValidVersion(pkg.Version) {
+			o.report(RuleInvalidVersion, path, scope, "%s %s: invalid version %q: it must be a semantic version or a version range", pkg.PackageType(), pkg.GetName(), pkg.Version)
+		}
+		return
+	}
+
+	if _, err := util.ParseVersionRange(pkg.Version); err != nil {
+		o.report(RuleInvalidVersionRange, path, scope, "%s %s: %s", pkg.PackageType(), pkg.GetName(), err)
+	}
+}
+
+// checkCatalogue checks that the enabled packages with a valid version exist in their repositories at the requested
+// version, and that the modules contain the requested flavor. The version ranges are checked against the version
+// they resolve to
+func (o *Options) checkCatalogue(spec v1alpha1.ConfigSpec) {
+	versionsCache := util.NewVersionsCache(o.filesGetter.ListVersions)
+	results := make(map[v1alpha1.Package]error)
+	_ = util.WalkPackages(spec, func(ref util.PackageRef, scope string, pkg v1alpha1.Package) error {
+		if pkg.Disable || !o.matchesSelector(spec, ref) {
+			return nil
+		}
+
+		if pkg.Source == (v1alpha1.Source{}) {
+			pkg.Source = spec.Source
+		}
+
+		path := util.PackagePath(ref)
+		if util.IsVersionRange(pkg.Version) {
+			if _, err := util.ParseVersionRange(pkg.Version); err != nil {
+				return nil
+			}
+
+			versions, err := versionsCache.Versions(pkg)
+			switch {
+			case errors.Is(err, git.ErrOffline):
+				o.logger.V(5).Info("skipping version range in offline mode", "package", pkg.GetName(), "range", pkg.Version)
+				return nil
+			case err != nil:
+				o.report(RuleCatalogueUnavailable, path+".version", scope, "%s %s: %s", pkg.PackageType(), pkg.GetName(), err)
+				return nil
+			}
+
+			version, err := util.ResolveVersion(pkg.Version, versions)
+			if err != nil {
+				o.report(RuleUnknownVersion, path+".version", scope, "%s %s: %s in its repository", pkg.PackageType(), pkg.GetName(), err)
+				return nil
+			}
+			pkg.Version = version
+		} else if !util.IsValidVersion(pkg.Version) {
+			return nil
+		}
+
+		err, checked := results[pkg]
+		if !checked {
+			o.logger.V(5).Info("checking package", "type", pkg.PackageType(), "package", pkg.GetName(), "version", pkg.Version)
+			err = o.filesGetter.CheckPackage(pkg)
+			results[pkg] = err
+		}
+
+		switch {
+		case err == nil:
+		case errors.Is(err, git.ErrTagNotFound):
+			o.report(RuleUnknownVersion, path+".version", scope, "%s %s %s: %s", pkg.PackageType(), pkg.GetName(), pkg.Version, err)
+		case errors.Is(err, git.ErrPackageNotFound):
+			o.report(RuleUnknownPackage, path, scope, "%s %s %s: %s", pkg.PackageType(), pkg.GetName(), pkg.Version, err)
+		case errors.Is(err, git.ErrFlavorNotFound):
+			o.report(RuleUnknownFlavor, path, scope, "%s %s %s: %s", pkg.PackageType(), pkg.GetName(), pkg.Version, err)
+		default:
+			o.report(RuleCatalogueUnavailable, path, scope, "%s %s: %s", pkg.PackageType(), pkg.GetName(), err)
+		}
+		return nil
+	})
+}
+
+// matchesSelector return true if the package referenced by ref is not defined in a cluster or if the cluster
+// matches the selector
+func (o *Options) matchesSelector(spec v1alpha1.ConfigSpec, ref util.PackageRef) bool {
+	if o.selector == nil || ref.Cluster < 0 {
+		return true
+	}
+
+	group := spec.Groups[ref.Group]
+	return o.selector.Matches(labels.Set(group.ClusterLabels(group.Clusters[ref.Cluster])))
+}
+
+// checkBuilds runs the kustomize build of the folder of every cluster matching the selector, running at most
+// o.jobs builds at the same time, and reports the clusters that fail. The folders are read from the project
+// containing the configuration file and the built manifests are discarded
+func (o *Options) checkBuilds(spec v1alpha1.ConfigSpec) {
+	contextPath := filepath.Dir(util.ConfigFilePath(o.configPath))
+	refs := make([]util.PackageRef, 0)
+	for groupIdx, group := range spec.Groups {
+		for clusterIdx, cluster := range group.Clusters {
+			ref := util.PackageRef{Group: groupIdx, Cluster: clusterIdx}
+			if len(group.Name) > 0 && len(cluster.Name) > 0 && o.matchesSelector(spec, ref) {
+				refs = append(refs, ref)
+			}
+		}
+	}
+
+	errs := make([]error, len(refs))
+	buildGroup := new(errgroup.Group)
+	buildGroup.SetLimit(max(o.jobs, 1))
+	for idx, ref := range refs {
+		group := spec.Groups[ref.Group]
+		cluster := group.Clusters[ref.Cluster]
+		buildGroup.Go(func() error {
+			o.logger.V(5).Info("building cluster", "cluster", util.ClusterID(group.Name, cluster.Name))
+			errs[idx] = util.WriteKustomizationData(filepath.Join(contextPath, util.ClusterPath(group.Name, cluster.Name)), io.Discard)
+			return nil
+		})
+	}
+	_ = buildGroup.Wait()
+
+	for idx, ref := range refs {
+		if errs[idx] != nil {
+			group := spec.Groups[ref.Group]
+			clusterID := util.ClusterID(group.Name, group.Clusters[ref.Cluster].Name)
+			o.report(RuleBuildFailed, util.ScopePath(ref.Group, ref.Cluster), clusterID, "building cluster: %s", errs[idx])
+		}
+	}
 }
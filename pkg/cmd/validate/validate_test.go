@@ -17,12 +17,22 @@ package validate
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"path/filepath"
-	"strings"
+	"slices"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/labels"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 
+	"github.com/mia-platform/vab/internal/git"
 	"github.com/mia-platform/vab/pkg/cmd/util"
 )
 
@@ -62,9 +72,9 @@ func TestValidationTextGeneration(t *testing.T) {
 			options: &Options{
 				configPath: filepath.Join(testdata, "empty_config.yaml"),
 			},
-			expectedString: `[warn][default] no module found: check the config file if this behavior is unexpected
-[warn][default] no addon found: check the config file if this behavior is unexpected
-[warn] no group found: check the config file if this behavior is unexpected
+			expectedString: `testdata/empty_config.yaml:5:3: warn: [default] no module found: check the config file if this behavior is unexpected
+testdata/empty_config.yaml:6:3: warn: [default] no addon found: check the config file if this behavior is unexpected
+testdata/empty_config.yaml:7:3: warn: no group found: check the config file if this behavior is unexpected
 The configuration is valid!
 `,
 		},
@@ -72,11 +82,11 @@ The configuration is valid!
 			options: &Options{
 				configPath: filepath.Join(testdata, "invalidkind.yaml"),
 			},
-			expectedString: `[error] wrong kind: WrongKind - expected: ClustersConfiguration
-[error] wrong version: wrong.version.io/v1 - expected: vab.mia-platform.eu/v1alpha1
-[warn][default] no module found: check the config file if this behavior is unexpected
-[warn][default] no addon found: check the config file if this behavior is unexpected
-[warn] no group found: check the config file if this behavior is unexpected
+			expectedString: `testdata/invalidkind.yaml:1:1: error: wrong kind: WrongKind - expected: ClustersConfiguration
+testdata/invalidkind.yaml:2:1: error: wrong version: wrong.version.io/v1 - expected: vab.mia-platform.eu/v1alpha1 or vab.mia-platform.eu/v1alpha2
+testdata/invalidkind.yaml:3:1: warn: [default] no module found: check the config file if this behavior is unexpected
+testdata/invalidkind.yaml:3:1: warn: [default] no addon found: check the config file if this behavior is unexpected
+testdata/invalidkind.yaml:3:1: warn: no group found: check the config file if this behavior is unexpected
 `,
 			expectedError: "configuration is invalid",
 		},
@@ -84,18 +94,161 @@ The configuration is valid!
 			options: &Options{
 				configPath: filepath.Join(testdata, "all-check-config.yaml"),
 			},
-			expectedString: `[error][default] missing version of module category/module-1
-[info][default] disabling module category/module-2
-[error][default] missing version of addon category/addon-1
-[info][default] disabling addon category/addon-2
-[error] please specify a valid name for each group
-[error][undefined] missing cluster name in group: please specify a valid name for each cluster
-[error][undefined/undefined] missing cluster context: please specify a valid context for each cluster
-[warn][undefined/undefined] no module found: check the config file if this behavior is unexpected
-[warn][undefined/undefined] no addon found: check the config file if this behavior is unexpected
-[warn][undefined/cluster-1] no module found: check the config file if this behavior is unexpected
-[warn][undefined/cluster-1] no addon found: check the config file if this behavior is unexpected
-[warn][group-1] no cluster found in group: check the config file if this behavior is unexpected
+			expectedString: `testdata/all-check-config.yaml:10:5: error: [default] missing version of module category/module-1
+testdata/all-check-config.yaml:13:7: info: [default] disabling module category/module-2
+testdata/all-check-config.yaml:19:5: error: [default] missing version of addon category/addon-1
+testdata/all-check-config.yaml:22:7: info: [default] disabling addon category/addon-2
+testdata/all-check-config.yaml:25:5: error: please specify a valid name for each group
+testdata/all-check-config.yaml:28:7: error: [undefined] missing cluster name in group: please specify a valid name for each cluster
+testdata/all-check-config.yaml:28:7: error: [undefined/undefined] missing cluster context: please specify a valid context for each cluster
+testdata/all-check-config.yaml:28:7: warn: [undefined/undefined] no module found: check the config file if this behavior is unexpected
+testdata/all-check-config.yaml:29:7: warn: [undefined/undefined] no addon found: check the config file if this behavior is unexpected
+testdata/all-check-config.yaml:32:7: warn: [undefined/cluster-1] no module found: check the config file if this behavior is unexpected
+testdata/all-check-config.yaml:32:7: warn: [undefined/cluster-1] no addon found: check the config file if this behavior is unexpected
+testdata/all-check-config.yaml:43:11: warn: [group-1/cluster-2] module category/module-0 overrides the inherited one with the same version 1.0.0
+testdata/all-check-config.yaml:45:9: error: [group-1/cluster-2] missing version of module category/module-1
+testdata/all-check-config.yaml:48:11: info: [group-1/cluster-2] disabling module category/module-2
+testdata/all-check-config.yaml:52:11: warn: [group-1/cluster-2] addon category/addon-0 overrides the inherited one with the same version 1.0.0
+testdata/all-check-config.yaml:54:9: error: [group-1/cluster-2] missing version of addon category/addon-1
+testdata/all-check-config.yaml:57:11: info: [group-1/cluster-2] disabling addon category/addon-2
+`,
+			expectedError: "configuration is invalid",
+		},
+		"unknown fields and duplicate keys": {
+			options: &Options{
+				configPath: filepath.Join(testdata, "unknown-fields.yaml"),
+			},
+			expectedString: `testdata/unknown-fields.yaml:7:7: error: unknown field "verison" in spec.modules.category/module-0/flavor-0
+testdata/unknown-fields.yaml:8:3: error: unknown field "addons" in spec
+testdata/unknown-fields.yaml:13:5: error: duplicate key "name" in spec.groups[0], already defined at line 12
+`,
+			expectedError: "configuration is invalid",
+		},
+		"conflicting module flavors": {
+			options: &Options{
+				configPath: filepath.Join(testdata, "flavors.yaml"),
+			},
+			expectedString: `testdata/flavors.yaml:8:5: error: module "ingress/traefik" has more than one flavor in spec.modules: "ingress/traefik/ha" conflicts with "ingress/traefik/base" defined at line 6
+testdata/flavors.yaml:24:9: error: module "cni/cilium" has more than one flavor in spec.groups[0].clusters[0].modules: "cni/cilium/ebpf" conflicts with "cni/cilium/base" defined at line 20
+`,
+			expectedError: "configuration is invalid",
+		},
+		"v1alpha2 config": {
+			options: &Options{
+				configPath: filepath.Join(testdata, "v1alpha2.yaml"),
+			},
+			expectedString: `testdata/v1alpha2.yaml:9:5: error: [default] missing flavor of module cni/cilium
+testdata/v1alpha2.yaml:17:7: warn: [test-group/test-cluster] no addon found: check the config file if this behavior is unexpected
+testdata/v1alpha2.yaml:21:11: info: [test-group/test-cluster] disabling module ingress/traefik
+`,
+			expectedError: "configuration is invalid",
+		},
+		"included files": {
+			options: &Options{
+				configPath: filepath.Join(testdata, "include", "config.yaml"),
+			},
+			expectedString: `testdata/include/config.yaml:15:7: warn: [production/cluster-1] no module found: check the config file if this behavior is unexpected
+testdata/include/config.yaml:15:7: warn: [production/cluster-1] no addon found: check the config file if this behavior is unexpected
+testdata/include/groups/development.yaml:3:3: warn: [development/cluster-1] no module found: check the config file if this behavior is unexpected
+testdata/include/groups/development.yaml:3:3: warn: [development/cluster-1] no addon found: check the config file if this behavior is unexpected
+testdata/include/groups/development/cluster-2.yaml:1:1: error: [development] duplicate cluster name "cluster-1": already used at line 3 of testdata/include/groups/development.yaml
+testdata/include/groups/development/cluster-2.yaml:1:1: warn: [development/cluster-1] no addon found: check the config file if this behavior is unexpected
+testdata/include/groups/development/cluster-2.yaml:4:3: error: [development/cluster-1] missing flavor of module cni/cilium
+testdata/include/groups/staging.yaml:1:1: error: duplicate group name "production": already used at line 13 of testdata/include/config.yaml
+testdata/include/groups/staging.yaml:1:1: warn: [production] no cluster found in group: check the config file if this behavior is unexpected
+`,
+			expectedError: "configuration is invalid",
+		},
+		"semantic checks": {
+			options: &Options{
+				configPath: filepath.Join(testdata, "semantic.yaml"),
+			},
+			expectedString: `testdata/semantic.yaml:7:7: error: [default] module category/module-0: invalid version "latest": it must be a semantic version or a version range
+testdata/semantic.yaml:18:9: warn: [group-1] addon category/addon-0 overrides the inherited one with the same version 1.0.0
+testdata/semantic.yaml:20:7: warn: [group-1/cluster-1] no addon found: check the config file if this behavior is unexpected
+testdata/semantic.yaml:26:7: error: [group-1] duplicate cluster name "cluster-1": already used at line 20
+testdata/semantic.yaml:26:7: warn: [group-1/cluster-1] no addon found: check the config file if this behavior is unexpected
+testdata/semantic.yaml:31:11: warn: [group-1/cluster-1] module category/module-1 overrides the inherited one with the same version 1.0.0
+testdata/semantic.yaml:32:7: error: [group-1] invalid cluster name "Cluster_2": a lowercase RFC 1123 label must consist of lower case alphanumeric characters or '-', and must start and end with an alphanumeric character (e.g. 'my-name',  or '123-abc', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?')
+testdata/semantic.yaml:32:7: warn: [group-1/Cluster_2] no module found: check the config file if this behavior is unexpected
+testdata/semantic.yaml:32:7: warn: [group-1/Cluster_2] no addon found: check the config file if this behavior is unexpected
+testdata/semantic.yaml:34:5: error: duplicate group name "group-1": already used at line 14
+testdata/semantic.yaml:36:7: warn: [group-1/cluster-1] no module found: check the config file if this behavior is unexpected
+testdata/semantic.yaml:36:7: warn: [group-1/cluster-1] no addon found: check the config file if this behavior is unexpected
+testdata/semantic.yaml:38:5: error: group name "all-groups" is reserved for the layer shared by all the groups
+testdata/semantic.yaml:38:5: warn: [all-groups] no cluster found in group: check the config file if this behavior is unexpected
+testdata/semantic.yaml:39:5: error: invalid group name "group.2": must not contain dots
+testdata/semantic.yaml:39:5: warn: [group.2] no cluster found in group: check the config file if this behavior is unexpected
+`,
+			expectedError: "configuration is invalid",
+		},
+		"invalid labels": {
+			options: &Options{
+				configPath: filepath.Join(testdata, "labels.yaml"),
+			},
+			expectedString: `testdata/labels.yaml:15:7: error: [group-1] invalid label key "invalid key": name part must consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character (e.g. 'MyName',  or 'my.name',  or '123-abc', regex used for validation is '([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9]')
+testdata/labels.yaml:17:7: warn: [group-1/cluster-1] no module found: check the config file if this behavior is unexpected
+testdata/labels.yaml:17:7: warn: [group-1/cluster-1] no addon found: check the config file if this behavior is unexpected
+testdata/labels.yaml:21:7: error: [group-1/cluster-2] missing cluster context: please specify a valid context for each cluster
+testdata/labels.yaml:21:7: warn: [group-1/cluster-2] no module found: check the config file if this behavior is unexpected
+testdata/labels.yaml:21:7: warn: [group-1/cluster-2] no addon found: check the config file if this behavior is unexpected
+testdata/labels.yaml:23:9: error: [group-1/cluster-2] invalid value for label "region": a valid label must be an empty string or consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character (e.g. 'MyValue',  or 'my_value',  or '12345', regex used for validation is '(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])?')
+`,
+			expectedError: "configuration is invalid",
+		},
+		"group packages and reserved cluster name": {
+			options: &Options{
+				configPath: filepath.Join(testdata, "group-packages.yaml"),
+			},
+			expectedString: `testdata/group-packages.yaml:14:7: error: [group-1] missing version of module category/module-1
+testdata/group-packages.yaml:17:9: info: [group-1] disabling addon category/addon-0
+testdata/group-packages.yaml:19:7: error: [group-1] cluster name "all-clusters" is reserved for the layer shared by all the clusters of the group
+testdata/group-packages.yaml:19:7: warn: [group-1/all-clusters] no module found: check the config file if this behavior is unexpected
+testdata/group-packages.yaml:19:7: warn: [group-1/all-clusters] no addon found: check the config file if this behavior is unexpected
+`,
+			expectedError: "configuration is invalid",
+		},
+		"invalid version ranges": {
+			options: &Options{
+				configPath: filepath.Join(testdata, "version-ranges.yaml"),
+			},
+			expectedString: `testdata/version-ranges.yaml:18:11: error: [group-1/cluster-1] module category/module-1: invalid version range "^a.b": Invalid character(s) found in major number "a"
+testdata/version-ranges.yaml:21:11: error: [group-1/cluster-1] addon category/addon-1: invalid version range ">>1.0.0": Could not parse Range ">>1.0.0": Could not parse comparator ">>" in ">>1.0.0"
+`,
+			expectedError: "configuration is invalid",
+		},
+		"selector skip not matching clusters": {
+			options: &Options{
+				configPath: filepath.Join(testdata, "labels.yaml"),
+				selector: func() labels.Selector {
+					selector, err := util.ParseSelector("env=prod,region=eu")
+					require.NoError(t, err)
+					return selector
+				}(),
+			},
+			expectedString: `testdata/labels.yaml:15:7: error: [group-1] invalid label key "invalid key": name part must consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character (e.g. 'MyName',  or 'my.name',  or '123-abc', regex used for validation is '([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9]')
+testdata/labels.yaml:17:7: warn: [group-1/cluster-1] no module found: check the config file if this behavior is unexpected
+testdata/labels.yaml:17:7: warn: [group-1/cluster-1] no addon found: check the config file if this behavior is unexpected
+`,
+			expectedError: "configuration is invalid",
+		},
+		"packages missing from the catalogue": {
+			options: &Options{
+				configPath: filepath.Join(testdata, "catalogue.yaml"),
+				selector: func() labels.Selector {
+					selector, err := util.ParseSelector("env!=test")
+					require.NoError(t, err)
+					return selector
+				}(),
+				filesGetter: func() *git.FilesGetter {
+					filesGetter, _ := git.NewTestFilesGetter(t)
+					return filesGetter
+				}(),
+			},
+			expectedString: `testdata/catalogue.yaml:8:5: error: [default] module category/test-module2 1.0.0: flavor not found: missing folder modules/category/test-module2/missing-flavor in tag module-category-test-module2-1.0.0
+testdata/catalogue.yaml:13:5: error: [default] addon category/missing-addon 1.0.0: package not found: missing folder addons/category/missing-addon in tag addon-category-missing-addon-1.0.0
+testdata/catalogue.yaml:21:9: error: [group-1/cluster-1] module category/missing-module 1.1.0: package not found: missing folder modules/category/missing-module in tag module-category-missing-module-1.1.0
+testdata/catalogue.yaml:25:11: error: [group-1/cluster-1] addon category/test-addon2: no version matches "^3.0.0" in its repository
 `,
 			expectedError: "configuration is invalid",
 		},
@@ -115,12 +268,277 @@ The configuration is valid!
 				assert.NoError(t, err)
 			}
 
-			loggedLines := strings.Split(buffer.String(), "\n")
-			expectedOutputArray := strings.Split(test.expectedString, "\n")
-			assert.Len(t, loggedLines, len(expectedOutputArray), "Wrong log lines founded")
-			for _, line := range loggedLines {
-				assert.Contains(t, expectedOutputArray, line, "Unexpected log line")
+			assert.Equal(t, test.expectedString, buffer.String())
+		})
+	}
+}
+
+func TestOutputFormats(t *testing.T) {
+	t.Parallel()
+	testdata := "testdata"
+
+	tests := map[string]struct {
+		configPath     string
+		output         string
+		expectedOutput string
+		expectedError  string
+	}{
+		"json output": {
+			configPath:     filepath.Join(testdata, "version-ranges.yaml"),
+			output:         "json",
+			expectedOutput: filepath.Join(testdata, "version-ranges.json"),
+			expectedError:  "configuration is invalid",
+		},
+		"json output of valid configuration": {
+			configPath:     filepath.Join(testdata, "valid.yaml"),
+			output:         "json",
+			expectedOutput: filepath.Join(testdata, "valid.json"),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			buffer := new(bytes.Buffer)
+			configFlags := util.NewConfigFlags()
+			configFlags.ConfigPath = &test.configPath
+			options, err := (&Flags{output: test.output}).ToOptions(configFlags, buffer)
+			require.NoError(t, err)
+
+			err = options.Run(t.Context())
+			if len(test.expectedError) > 0 {
+				assert.ErrorContains(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
 			}
+
+			expectedOutput, err := os.ReadFile(test.expectedOutput)
+			require.NoError(t, err)
+			assert.Equal(t, string(expectedOutput), buffer.String())
 		})
 	}
 }
+
+func TestSARIFOutput(t *testing.T) {
+	t.Parallel()
+
+	buffer := new(bytes.Buffer)
+	configPath := filepath.Join("testdata", "unknown-fields.yaml")
+	configFlags := util.NewConfigFlags()
+	configFlags.ConfigPath = &configPath
+	options, err := (&Flags{output: outputSARIF}).ToOptions(configFlags, buffer)
+	require.NoError(t, err)
+
+	err = options.Run(t.Context())
+	assert.ErrorContains(t, err, "configuration is invalid")
+
+	// the rules are checked by TestSARIFRules, the golden file contains only the results
+	var output map[string]any
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &output))
+	for _, run := range output["runs"].([]any) {
+		driver := run.(map[string]any)["tool"].(map[string]any)["driver"].(map[string]any)
+		delete(driver, "rules")
+	}
+	data, err := json.Marshal(output)
+	require.NoError(t, err)
+
+	expectedOutput, err := os.ReadFile(filepath.Join("testdata", "unknown-fields.sarif"))
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expectedOutput), string(data))
+}
+
+func TestSARIFRules(t *testing.T) {
+	t.Parallel()
+
+	buffer := new(bytes.Buffer)
+	require.NoError(t, writeSARIF(buffer, nil))
+
+	var log sarifLog
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &log))
+	require.Len(t, log.Runs, 1)
+
+	expectedRules := make([]sarifRule, 0, len(rules))
+	for _, id := range slices.Sorted(maps.Keys(rules)) {
+		expectedRules = append(expectedRules, sarifRule{
+			ID:                   id,
+			ShortDescription:     sarifMessage{Text: rules[id].description},
+			DefaultConfiguration: sarifConfiguration{Level: sarifLevels[rules[id].severity]},
+		})
+	}
+	assert.Equal(t, expectedRules, log.Runs[0].Tool.Driver.Rules)
+	for _, rule := range log.Runs[0].Tool.Driver.Rules {
+		assert.NotEmpty(t, rule.ShortDescription.Text, rule.ID)
+		assert.NotEmpty(t, rule.DefaultConfiguration.Level, rule.ID)
+	}
+	assert.Empty(t, log.Runs[0].Results)
+}
+
+func TestInvalidOutputFormat(t *testing.T) {
+	t.Parallel()
+
+	_, err := (&Flags{output: "xml"}).ToOptions(util.NewConfigFlags(), new(bytes.Buffer))
+	assert.EqualError(t, err, `invalid output format "xml": must be one of text, json, sarif`)
+}
+
+func TestOfflineNeedsRemote(t *testing.T) {
+	t.Parallel()
+
+	_, err := (&Flags{offline: true}).ToOptions(util.NewConfigFlags(), new(bytes.Buffer))
+	assert.EqualError(t, err, "the --offline flag needs the --remote flag")
+
+	options, err := (&Flags{remote: true, offline: true, cacheDir: t.TempDir()}).ToOptions(util.NewConfigFlags(), new(bytes.Buffer))
+	require.NoError(t, err)
+	assert.NotNil(t, options.filesGetter)
+}
+
+func TestBuildClusters(t *testing.T) {
+	t.Parallel()
+	configPath := filepath.Join("testdata", "build", "config.yaml")
+
+	tests := map[string]struct {
+		selector       string
+		expectedScopes []string
+		expectedLines  []int
+	}{
+		"build all clusters": {
+			expectedScopes: []string{"group-1/cluster-2", "group-1/cluster-3"},
+			expectedLines:  []int{12, 14},
+		},
+		"build only clusters matching selector": {
+			selector:       "env!=test",
+			expectedScopes: []string{"group-1/cluster-2"},
+			expectedLines:  []int{12},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			selector, err := util.ParseSelector(test.selector)
+			require.NoError(t, err)
+			options := &Options{
+				configPath: configPath,
+				selector:   selector,
+				writer:     new(bytes.Buffer),
+				build:      true,
+				jobs:       2,
+			}
+			assert.EqualError(t, options.Run(t.Context()), "configuration is invalid")
+
+			scopes := make([]string, 0)
+			lines := make([]int, 0)
+			for _, finding := range options.findings {
+				if finding.Rule == RuleBuildFailed {
+					assert.Equal(t, SeverityError, finding.Severity)
+					assert.Contains(t, finding.Message, "building cluster: ")
+					scopes = append(scopes, finding.Scope)
+					lines = append(lines, finding.Line)
+				}
+			}
+			assert.Equal(t, test.expectedScopes, scopes)
+			assert.Equal(t, test.expectedLines, lines)
+		})
+	}
+}
+
+func TestInvalidJobs(t *testing.T) {
+	t.Parallel()
+
+	_, err := (&Flags{build: true}).ToOptions(util.NewConfigFlags(), new(bytes.Buffer))
+	assert.EqualError(t, err, "invalid jobs 0: must be greater than zero")
+}
+
+func TestCheckContexts(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"major":"1","minor":"34","gitVersion":"v1.34.1"}`))
+	}))
+	t.Cleanup(server.Close)
+	offlineServer := httptest.NewServer(http.NotFoundHandler())
+	offlineServer.Close()
+
+	kubeConfig := clientcmdapi.NewConfig()
+	kubeConfig.Clusters["online"] = &clientcmdapi.Cluster{Server: server.URL}
+	kubeConfig.Clusters["offline"] = &clientcmdapi.Cluster{Server: offlineServer.URL}
+	kubeConfig.AuthInfos["user"] = &clientcmdapi.AuthInfo{}
+	kubeConfig.Contexts["kind-online"] = &clientcmdapi.Context{Cluster: "online", AuthInfo: "user"}
+	kubeConfig.Contexts["kind-offline"] = &clientcmdapi.Context{Cluster: "offline", AuthInfo: "user"}
+
+	tests := map[string]struct {
+		selector         string
+		online           bool
+		expectedFindings []string
+	}{
+		"check contexts": {
+			expectedFindings: []string{
+				"group-1/cluster-2 duplicate-cluster-context 13",
+				"group-1/cluster-3 unknown-cluster-context 15",
+			},
+		},
+		"check contexts online": {
+			online: true,
+			expectedFindings: []string{
+				"group-1/cluster-1 cluster-version 11",
+				"group-1/cluster-2 duplicate-cluster-context 13",
+				"group-1/cluster-3 unknown-cluster-context 15",
+				"group-2/cluster-1 unreachable-cluster 19",
+			},
+		},
+		"check contexts online of clusters matching selector": {
+			selector: "env=test",
+			online:   true,
+			expectedFindings: []string{
+				"group-2/cluster-1 unreachable-cluster 19",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			selector, err := util.ParseSelector(test.selector)
+			require.NoError(t, err)
+			options := &Options{
+				configPath:     filepath.Join("testdata", "contexts.yaml"),
+				selector:       selector,
+				writer:         new(bytes.Buffer),
+				jobs:           2,
+				online:         test.online,
+				loadKubeConfig: func() (*clientcmdapi.Config, error) { return kubeConfig, nil },
+			}
+			_ = options.Run(t.Context())
+
+			findings := make([]string, 0)
+			for _, finding := range options.findings {
+				switch finding.Rule {
+				case RuleUnknownClusterContext, RuleDuplicateClusterContext, RuleUnreachableCluster, RuleClusterVersion:
+					findings = append(findings, fmt.Sprintf("%s %s %d", finding.Scope, finding.Rule, finding.Line))
+				}
+			}
+			assert.Equal(t, test.expectedFindings, findings)
+		})
+	}
+}
+
+func TestContextsFlags(t *testing.T) {
+	t.Parallel()
+
+	options, err := (&Flags{}).ToOptions(util.NewConfigFlags(), new(bytes.Buffer))
+	require.NoError(t, err)
+	assert.Nil(t, options.loadKubeConfig)
+
+	options, err = (&Flags{contexts: true}).ToOptions(util.NewConfigFlags(), new(bytes.Buffer))
+	require.NoError(t, err)
+	assert.NotNil(t, options.loadKubeConfig)
+	assert.False(t, options.online)
+
+	options, err = (&Flags{online: true, jobs: 1}).ToOptions(util.NewConfigFlags(), new(bytes.Buffer))
+	require.NoError(t, err)
+	assert.NotNil(t, options.loadKubeConfig)
+	assert.True(t, options.online)
+}